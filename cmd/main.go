@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/alexedwards/scs/v2"
 	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/journal"
 	"github.com/overdone/stubrouter/internal/routes"
 	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
 	"log"
 	"net/http"
 	"os"
@@ -18,6 +20,8 @@ var revision string
 
 var cfg config.StubRouterConfig
 var stubStore stubs.StubStorage
+var targetRegistry *targets.Registry
+var requestJournal journal.Journal
 var sessionManager *scs.SessionManager
 
 func init() {
@@ -51,6 +55,29 @@ func init() {
 		log.Fatalf(">>> Init stub store error: %s", err)
 	}
 
+	log.Println("-- Init targets --")
+	targetRegistry, err = targets.NewRegistry(&cfg)
+	if err != nil {
+		log.Fatalf(">>> Config error. %s", err)
+	}
+
+	log.Println("-- Init request journal --")
+	if cfg.Journal.Size <= 0 {
+		log.Fatal(">>> Config error. Journal size must be positive")
+	}
+	switch cfg.Journal.Type {
+	case "memory":
+		requestJournal = &journal.MemoryJournal{Size: cfg.Journal.Size}
+	case "redis":
+		requestJournal = &journal.RedisJournal{ConnString: cfg.Journal.Path, Size: cfg.Journal.Size}
+	default:
+		log.Fatalf(">>> Config error. Journal type %s not supported", cfg.Journal.Type)
+	}
+	err = requestJournal.InitJournal(&cfg)
+	if err != nil {
+		log.Fatalf(">>> Init request journal error: %s", err)
+	}
+
 	log.Println("-- Init session manager --")
 	sessionManager = scs.New()
 	sessionManager.Lifetime, err = time.ParseDuration(cfg.Session.Duration)
@@ -66,12 +93,12 @@ func init() {
 }
 
 func main() {
-	handler := routes.Routes(&cfg, sessionManager, stubStore)
+	handler := routes.Routes(&cfg, sessionManager, stubStore, targetRegistry, requestJournal)
 
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
 
 	log.Printf("-- Start proxy server on %s --", addr)
-	if err := http.ListenAndServe(addr, sessionManager.LoadAndSave(handler)); err != nil {
+	if err := http.ListenAndServe(addr, handler); err != nil {
 		log.Printf(">>> Fail start server on %s", addr)
 		log.Printf("Error: %s", err)
 	}
@@ -22,7 +22,16 @@ type StubRouterConfig struct {
 		UseridField string `long:"user-field" description:"Auth user field in JWT token"`
 	} `group:"auth" namespace:"auth"`
 
-	Targets map[string]string `short:"t" long:"target" description:"Target pair target_path:target_host"`
+	Targets   map[string]string `short:"t" long:"target" description:"Target pair target_path:target_host"`
+	Modes     map[string]string `long:"mode" description:"Target mode pair target_path:mode (hybrid, stub, passthrough, record)"`
+	Record    []string          `long:"record" description:"Target path to record upstream responses as stubs, same as record mode"`
+	Chaos     map[string]string `long:"chaos" description:"Target chaos rules pair target_path:rules_file"`
+	Bandwidth map[string]int    `long:"bandwidth" description:"Target bandwidth limit pair target_path:bytes_per_second"`
+	Contracts map[string]string `long:"openapi" description:"Target OpenAPI contract pair target_path:spec_file"`
+
+	ValidateUpstream []string `long:"validate-upstream" description:"Target path to validate upstream responses against its OpenAPI contract"`
+
+	UnmatchedCode int `long:"unmatched-code" default:"404" description:"Response code for requests without stub in stub mode"`
 
 	StubsStorage struct {
 		Type  string `long:"type" default:"file" description:"Stub storage type: file, redis"`
@@ -33,6 +42,13 @@ type StubRouterConfig struct {
 			CleanupInterval    string `long:"cleanup-interval" default:"60m" description:"Remove stub from cache after"`
 		} `group:"cache" namespace:"cache"`
 	} `group:"stubs" namespace:"stubs"`
+
+	Journal struct {
+		Type    string `long:"type" default:"memory" description:"Request journal type: memory, redis"`
+		Path    string `long:"path" description:"Request journal redis connect string"`
+		Size    int    `long:"size" default:"1000" description:"Number of last requests kept in journal"`
+		MaxBody int    `long:"max-body" default:"65536" description:"Request body bytes kept in journal"`
+	} `group:"journal" namespace:"journal"`
 }
 
 // normalize fix params and set defaults
@@ -44,6 +60,37 @@ func normalize(cfg *StubRouterConfig) error {
 	}
 	cfg.Targets = fixedTargets
 
+	fixedModes := make(map[string]string)
+	for k, v := range cfg.Modes {
+		fixedModes[path.Clean("/"+k)] = v
+	}
+	for _, v := range cfg.Record {
+		fixedModes[path.Clean("/"+v)] = "record"
+	}
+	cfg.Modes = fixedModes
+
+	fixedChaos := make(map[string]string)
+	for k, v := range cfg.Chaos {
+		fixedChaos[path.Clean("/"+k)] = v
+	}
+	cfg.Chaos = fixedChaos
+
+	fixedBandwidth := make(map[string]int)
+	for k, v := range cfg.Bandwidth {
+		fixedBandwidth[path.Clean("/"+k)] = v
+	}
+	cfg.Bandwidth = fixedBandwidth
+
+	fixedContracts := make(map[string]string)
+	for k, v := range cfg.Contracts {
+		fixedContracts[path.Clean("/"+k)] = v
+	}
+	cfg.Contracts = fixedContracts
+
+	for i, v := range cfg.ValidateUpstream {
+		cfg.ValidateUpstream[i] = path.Clean("/" + v)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,367 @@
+package curl
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Command HTTP request described by curl command line
+type Command struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// valueFlags Flags with value, only request related ones are used
+var valueFlags = map[string]bool{
+	"-X": true, "--request": true, "-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-raw": true, "--data-binary": true, "--data-ascii": true, "--data-urlencode": true,
+	"--json": true, "--url": true, "-u": true, "--user": true, "-b": true, "--cookie": true,
+	"-A": true, "--user-agent": true, "-e": true, "--referer": true,
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true, "-w": true, "--write-out": true,
+	"--cacert": true, "--cert": true, "--key": true, "-x": true, "--proxy": true, "--retry": true, "-F": true, "--form": true,
+	"--resolve": true, "--limit-rate": true, "-c": true, "--cookie-jar": true,
+}
+
+// Parse Read curl commands separated by new lines, command may continue on next line after backslash.
+// Single, double and ANSI-C $'...' quoting is supported. Commands in Windows cmd format, like DevTools
+// "Copy as cURL (cmd)" gives, continue after caret and have caret escaped chars
+func Parse(text string) ([]Command, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	split := splitShell
+	if isCmdFormat(text) {
+		split = splitCmd
+	}
+	commands, err := split(text)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Command, 0, len(commands))
+	for i, args := range commands {
+		cmd, err := parseArgs(args)
+		if err != nil {
+			return nil, fmt.Errorf("command %d: %s", i+1, err)
+		}
+		result = append(result, cmd)
+	}
+
+	return result, nil
+}
+
+// isCmdFormat Check commands are written for Windows cmd: quotes or line ends are caret escaped
+func isCmdFormat(text string) bool {
+	return strings.Contains(text, "^\"") || strings.Contains(text, " ^\n")
+}
+
+// splitShell Split text to commands of shell words
+func splitShell(text string) ([][]string, error) {
+	var commands [][]string
+	var args []string
+	var word strings.Builder
+	inWord := false
+
+	endWord := func() {
+		if inWord {
+			args = append(args, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(args) > 0 {
+			commands = append(commands, args)
+			args = nil
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\' && i+1 < len(runes) && runes[i+1] == '\n':
+			// Line continuation
+			i++
+		case c == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case c == '\n' || c == ';':
+			endCommand()
+		case c == ' ' || c == '\t':
+			endWord()
+		case c == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			n, err := ansiQuoted(runes, i+2, &word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i = n
+		case c == '"':
+			n, err := doubleQuoted(runes, i+1, &word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i = n
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	endCommand()
+
+	return commands, nil
+}
+
+// splitCmd Split Windows cmd text to commands of words. Caret escapes next char and continues line
+// before line end, after that words are split by program arguments rules: double quotes group words,
+// backslash escapes quote and backslash
+func splitCmd(text string) ([][]string, error) {
+	var unescaped []rune
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '^' || i+1 == len(runes) {
+			unescaped = append(unescaped, runes[i])
+			continue
+		}
+		i++
+		if runes[i] != '\n' {
+			unescaped = append(unescaped, runes[i])
+		}
+	}
+
+	var commands [][]string
+	var args []string
+	var word strings.Builder
+	inWord, quoted := false, false
+
+	endWord := func() {
+		if inWord {
+			args = append(args, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(unescaped); i++ {
+		c := unescaped[i]
+		switch {
+		case c == '\\' && i+1 < len(unescaped) && (unescaped[i+1] == '"' || unescaped[i+1] == '\\'):
+			i++
+			word.WriteRune(unescaped[i])
+			inWord = true
+		case c == '"':
+			quoted = !quoted
+			inWord = true
+		case quoted:
+			word.WriteRune(c)
+		case c == '\n':
+			endWord()
+			if len(args) > 0 {
+				commands = append(commands, args)
+				args = nil
+			}
+		case c == ' ' || c == '\t':
+			endWord()
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	endWord()
+	if len(args) > 0 {
+		commands = append(commands, args)
+	}
+
+	return commands, nil
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// doubleQuoted Read double quoted string, returns closing quote index
+func doubleQuoted(runes []rune, from int, word *strings.Builder) (int, error) {
+	for i := from; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == '"':
+			return i, nil
+		case c == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+			}
+		default:
+			word.WriteRune(c)
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated quote")
+}
+
+// ansiQuoted Read $'...' string with C escapes, returns closing quote index
+func ansiQuoted(runes []rune, from int, word *strings.Builder) (int, error) {
+	escapes := map[rune]string{'n': "\n", 't': "\t", 'r': "\r", '\\': "\\", '\'': "'", '"': "\"", '0': "\x00"}
+
+	for i := from; i < len(runes); i++ {
+		c := runes[i]
+		if c == '\'' {
+			return i, nil
+		}
+		if c != '\\' || i+1 >= len(runes) {
+			word.WriteRune(c)
+			continue
+		}
+
+		i++
+		e := runes[i]
+		switch {
+		case escapes[e] != "":
+			word.WriteString(escapes[e])
+		case (e == 'x' || e == 'u') && i+2 < len(runes):
+			size := 2
+			if e == 'u' {
+				size = 4
+			}
+			if i+size >= len(runes) {
+				return 0, fmt.Errorf("invalid escape")
+			}
+			code, err := strconv.ParseUint(string(runes[i+1:i+1+size]), 16, 32)
+			if err != nil {
+				return 0, fmt.Errorf("invalid escape \\%c%s", e, string(runes[i+1:i+1+size]))
+			}
+			if e == 'x' {
+				word.WriteByte(byte(code))
+			} else {
+				word.WriteRune(rune(code))
+			}
+			i += size
+		default:
+			word.WriteRune('\\')
+			word.WriteRune(e)
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated quote")
+}
+
+// parseArgs Build request from curl arguments
+func parseArgs(args []string) (Command, error) {
+	if args[0] != "curl" && args[0] != "curl.exe" {
+		return Command{}, fmt.Errorf("curl command expected, got %s", args[0])
+	}
+
+	cmd := Command{Header: make(http.Header)}
+	var data []string
+	get := false
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			cmd.URL = arg
+			continue
+		}
+
+		flag, value := arg, ""
+		if valueFlags[arg] {
+			if i+1 >= len(args) {
+				return cmd, fmt.Errorf("flag %s requires value", arg)
+			}
+			i++
+			value = args[i]
+		} else if len(arg) > 2 && arg[1] != '-' && valueFlags[arg[:2]] {
+			// Value attached to short flag like -XPOST
+			flag, value = arg[:2], arg[2:]
+		}
+
+		switch flag {
+		case "-X", "--request":
+			cmd.Method = strings.ToUpper(value)
+		case "-H", "--header":
+			name, v, ok := strings.Cut(value, ":")
+			if ok {
+				cmd.Header.Add(strings.TrimSpace(name), strings.TrimSpace(v))
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			data = append(data, value)
+		case "--data-urlencode":
+			if name, v, ok := strings.Cut(value, "="); ok {
+				data = append(data, name+"="+url.QueryEscape(v))
+			} else {
+				data = append(data, url.QueryEscape(value))
+			}
+		case "--json":
+			data = append(data, value)
+			cmd.Header.Set("Content-Type", "application/json")
+			cmd.Header.Set("Accept", "application/json")
+		case "--url":
+			cmd.URL = value
+		case "-u", "--user":
+			cmd.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-b", "--cookie":
+			cmd.Header.Add("Cookie", value)
+		case "-A", "--user-agent":
+			cmd.Header.Set("User-Agent", value)
+		case "-e", "--referer":
+			cmd.Header.Set("Referer", value)
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			cmd.Method = http.MethodHead
+		}
+	}
+
+	if cmd.URL == "" {
+		return cmd, fmt.Errorf("url not found")
+	}
+	if !strings.Contains(cmd.URL, "://") {
+		cmd.URL = "http://" + cmd.URL
+	}
+
+	switch {
+	case get && len(data) > 0:
+		sep := "?"
+		if strings.Contains(cmd.URL, "?") {
+			sep = "&"
+		}
+		cmd.URL += sep + strings.Join(data, "&")
+	case len(data) > 0:
+		cmd.Body = strings.Join(data, "&")
+		if cmd.Method == "" {
+			cmd.Method = http.MethodPost
+		}
+		if cmd.Header.Get("Content-Type") == "" {
+			cmd.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if cmd.Method == "" {
+		cmd.Method = http.MethodGet
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,199 @@
+package curl
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitShell(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want [][]string
+	}{
+		{"plain words", "curl -s http://a/b", [][]string{{"curl", "-s", "http://a/b"}}},
+		{"single quotes", `curl 'http://a/b?x=1&y=2' -H 'A: "q"'`, [][]string{{"curl", "http://a/b?x=1&y=2", "-H", `A: "q"`}}},
+		{"double quotes", `curl "a \"b\" \$c \\d 'e'"`, [][]string{{"curl", `a "b" $c \d 'e'`}}},
+		{"ansi quotes", `curl $'it\'s\n\x41é\t!'`, [][]string{{"curl", "it's\nAé\t!"}}},
+		{"adjacent quotes join word", `curl a'b'"c"`, [][]string{{"curl", "abc"}}},
+		{"escaped space", `curl a\ b`, [][]string{{"curl", "a b"}}},
+		{"line continuation", "curl \\\n  -X POST \\\r\n  http://a", [][]string{{"curl", "-X", "POST", "http://a"}}},
+		{"several commands", "curl a\n\ncurl b; curl c\n", [][]string{{"curl", "a"}, {"curl", "b"}, {"curl", "c"}}},
+		{"empty quoted word", `curl -d '' a`, [][]string{{"curl", "-d", "", "a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShell(strings.ReplaceAll(tt.text, "\r\n", "\n"))
+			if err != nil {
+				t.Fatalf("splitShell error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShell = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want [][]string
+	}{
+		{"caret quotes", `curl ^"http://a/b?x=1^&y=2^"`, [][]string{{"curl", "http://a/b?x=1&y=2"}}},
+		{"line continuation", "curl ^\"http://a^\" ^\n  -X POST", [][]string{{"curl", "http://a", "-X", "POST"}}},
+		{"escaped quote and backslash", `curl -d ^"^{^\^"a^\^":^\^"b^\^\^\^\c^\^"^}^"`, [][]string{{"curl", "-d", `{"a":"b\\c"}`}}},
+		{"newline in quotes", "curl -d ^\"a^\n\nb^\" x", [][]string{{"curl", "-d", "a\nb", "x"}}},
+		{"percent", `curl ^"http://a/b?q=%^25^"`, [][]string{{"curl", "http://a/b?q=%25"}}},
+		{"plain quotes", `curl "a b" c`, [][]string{{"curl", "a b", "c"}}},
+		{"several commands", "curl ^\"a^\"\ncurl ^\"b^\"", [][]string{{"curl", "a"}, {"curl", "b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCmd(tt.text)
+			if err != nil {
+				t.Fatalf("splitCmd error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCmd = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	for _, text := range []string{`curl 'a`, `curl "a`, `curl $'a`, `curl $'\uZZZZ'`} {
+		if _, err := splitShell(text); err == nil {
+			t.Errorf("splitShell(%q) accepted", text)
+		}
+	}
+	if _, err := splitCmd(`curl ^"a`); err == nil {
+		t.Error("splitCmd accepted unterminated quote")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want Command
+	}{
+		{"get", []string{"curl", "http://a/b"},
+			Command{Method: "GET", URL: "http://a/b", Header: http.Header{}}},
+		{"url without scheme", []string{"curl", "--url", "a/b"},
+			Command{Method: "GET", URL: "http://a/b", Header: http.Header{}}},
+		{"method and headers", []string{"curl", "-X", "put", "-H", "Accept: text/plain", "-H", "X-A:1", "-H", "bad", "http://a"},
+			Command{Method: "PUT", URL: "http://a", Header: http.Header{"Accept": {"text/plain"}, "X-A": {"1"}}}},
+		{"attached method", []string{"curl", "-XDELETE", "http://a"},
+			Command{Method: "DELETE", URL: "http://a", Header: http.Header{}}},
+		{"data", []string{"curl", "http://a", "-d", "x=1", "--data-raw", "y=2"},
+			Command{Method: "POST", URL: "http://a", Body: "x=1&y=2",
+				Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}}},
+		{"data urlencode", []string{"curl", "http://a", "--data-urlencode", "q=a b&c"},
+			Command{Method: "POST", URL: "http://a", Body: "q=a+b%26c",
+				Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}}},
+		{"json", []string{"curl", "--json", `{"a":1}`, "http://a"},
+			Command{Method: "POST", URL: "http://a", Body: `{"a":1}`,
+				Header: http.Header{"Content-Type": {"application/json"}, "Accept": {"application/json"}}}},
+		{"data with method", []string{"curl", "-X", "PATCH", "-H", "Content-Type: text/plain", "-d", "hi", "http://a"},
+			Command{Method: "PATCH", URL: "http://a", Body: "hi", Header: http.Header{"Content-Type": {"text/plain"}}}},
+		{"get data", []string{"curl", "-G", "-d", "x=1", "http://a?y=2"},
+			Command{Method: "GET", URL: "http://a?y=2&x=1", Header: http.Header{}}},
+		{"head", []string{"curl", "-I", "http://a"},
+			Command{Method: "HEAD", URL: "http://a", Header: http.Header{}}},
+		{"auth and cookies", []string{"curl", "-u", "user:pw", "-b", "s=1", "-A", "ua", "-e", "http://r", "http://a"},
+			Command{Method: "GET", URL: "http://a", Header: http.Header{
+				"Authorization": {"Basic dXNlcjpwdw=="}, "Cookie": {"s=1"}, "User-Agent": {"ua"}, "Referer": {"http://r"}}}},
+		{"ignored flags", []string{"curl.exe", "-s", "--compressed", "-o", "out.txt", "-m", "5", "http://a"},
+			Command{Method: "GET", URL: "http://a", Header: http.Header{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseArgs error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseArgs = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"wget", "http://a"}, "curl command expected"},
+		{[]string{"curl", "-s"}, "url not found"},
+		{[]string{"curl", "http://a", "-H"}, "requires value"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseArgs(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseArgs(%q) error = %v, want %q", tt.args, err, tt.err)
+		}
+	}
+}
+
+// DevTools "Copy as cURL" output for the same request in bash and cmd formats
+const devToolsBash = `curl 'https://shop.example.com/app1/api/cart?id=7' \
+  -H 'accept: application/json' \
+  -H 'content-type: application/json' \
+  -H 'cookie: session=abc' \
+  -H $'user-agent: Mozilla/5.0 \'x\'' \
+  --data-raw $'{"name":"it\'s","qty":2}' \
+  --compressed`
+
+const devToolsCmd = "curl ^\"https://shop.example.com/app1/api/cart?id=7^\" ^\r\n" +
+	"  -H ^\"accept: application/json^\" ^\r\n" +
+	"  -H ^\"content-type: application/json^\" ^\r\n" +
+	"  -H ^\"cookie: session=abc^\" ^\r\n" +
+	"  -H ^\"user-agent: Mozilla/5.0 'x'^\" ^\r\n" +
+	"  --data-raw ^\"^{^\\^\"name^\\^\":^\\^\"it's^\\^\",^\\^\"qty^\\^\":2^}^\" ^\r\n" +
+	"  --compressed"
+
+func TestParseDevTools(t *testing.T) {
+	want := Command{
+		Method: "POST",
+		URL:    "https://shop.example.com/app1/api/cart?id=7",
+		Header: http.Header{
+			"Accept":       {"application/json"},
+			"Content-Type": {"application/json"},
+			"Cookie":       {"session=abc"},
+			"User-Agent":   {"Mozilla/5.0 'x'"},
+		},
+		Body: `{"name":"it's","qty":2}`,
+	}
+
+	for name, text := range map[string]string{"bash": devToolsBash, "cmd": devToolsCmd} {
+		t.Run(name, func(t *testing.T) {
+			commands, err := Parse(text + "\n" + text)
+			if err != nil {
+				t.Fatalf("Parse error: %s", err)
+			}
+			if len(commands) != 2 {
+				t.Fatalf("got %d commands, want 2", len(commands))
+			}
+			for _, cmd := range commands {
+				if !reflect.DeepEqual(cmd, want) {
+					t.Errorf("Parse = %+v, want %+v", cmd, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse("curl http://a\ncurl -s")
+	if err == nil || !strings.Contains(err.Error(), "command 2: url not found") {
+		t.Errorf("Parse error = %v, want command 2 error", err)
+	}
+}
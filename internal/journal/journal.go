@@ -0,0 +1,167 @@
+package journal
+
+import (
+	"fmt"
+	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry Request passed through router with its outcome
+type Entry struct {
+	ID            int64                `json:"id"`
+	Time          time.Time            `json:"time"`
+	Target        string               `json:"target"`
+	Method        string               `json:"method"`
+	Path          string               `json:"path"`
+	Query         url.Values           `json:"query"`
+	Headers       http.Header          `json:"headers"`
+	Body          string               `json:"body,omitempty"`
+	BodyEncoding  string               `json:"bodyEncoding,omitempty"`
+	BodyTruncated bool                 `json:"bodyTruncated,omitempty"`
+	Stubbed       bool                 `json:"stubbed"`
+	Stub          string               `json:"stub,omitempty"`
+	Unmatched     bool                 `json:"unmatched,omitempty"`
+	NearMisses    []stubs.StubNearMiss `json:"nearMisses,omitempty"`
+	Status        int                  `json:"status"`
+	Duration      float64              `json:"durationMs"`
+}
+
+// Journal Bounded history of requests, oldest entries are dropped first
+type Journal interface {
+	InitJournal(cfg *config.StubRouterConfig) error
+	// Record Add entry, entry ID is assigned on record
+	Record(e *Entry) error
+	// Entries Entries matching filter in record order
+	Entries(f Filter) ([]Entry, error)
+	// Unmatched Unmatched entries of target matching filter in record order. They are kept apart
+	// from other entries, last Size ones for every target, so other requests don`t evict them
+	Unmatched(target string, f Filter) ([]Entry, error)
+	Reset() error
+}
+
+// Filter Journal query. Zero fields match any entry, Path is stub path pattern
+type Filter struct {
+	Target  string
+	Method  string
+	Path    string
+	Status  int
+	Stubbed *bool
+	Stub    string
+	// Unmatched Requests no stub was found for
+	Unmatched *bool
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// parseTime Time given in RFC 3339 format or as unix milliseconds
+func parseTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// ParseFilter Filter from query params target, method, path, status, stubbed, stub, unmatched, since, until and limit
+func ParseFilter(q url.Values) (Filter, error) {
+	f := Filter{
+		Target: q.Get("target"),
+		Method: strings.ToUpper(q.Get("method")),
+		Path:   q.Get("path"),
+		Stub:   q.Get("stub"),
+	}
+
+	var err error
+	if v := q.Get("status"); v != "" {
+		if f.Status, err = strconv.Atoi(v); err != nil {
+			return f, fmt.Errorf("invalid status %s", v)
+		}
+	}
+	if v := q.Get("stubbed"); v != "" {
+		stubbed, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid stubbed flag %s", v)
+		}
+		f.Stubbed = &stubbed
+	}
+	if v := q.Get("unmatched"); v != "" {
+		unmatched, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid unmatched flag %s", v)
+		}
+		f.Unmatched = &unmatched
+	}
+	if v := q.Get("since"); v != "" {
+		if f.Since, err = parseTime(v); err != nil {
+			return f, fmt.Errorf("invalid since time %s", v)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.Until, err = parseTime(v); err != nil {
+			return f, fmt.Errorf("invalid until time %s", v)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return f, fmt.Errorf("invalid limit %s", v)
+		}
+	}
+	if f.Path != "" {
+		if err = stubs.ValidatePath(f.Path); err != nil {
+			return f, fmt.Errorf("invalid path pattern: %s", err)
+		}
+	}
+
+	return f, nil
+}
+
+// Matches Check entry satisfies filter
+func (f Filter) Matches(e *Entry) bool {
+	switch {
+	case f.Target != "" && e.Target != f.Target:
+		return false
+	case f.Method != "" && e.Method != f.Method:
+		return false
+	case f.Status != 0 && e.Status != f.Status:
+		return false
+	case f.Stubbed != nil && e.Stubbed != *f.Stubbed:
+		return false
+	case f.Stub != "" && e.Stub != f.Stub:
+		return false
+	case f.Unmatched != nil && e.Unmatched != *f.Unmatched:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && e.Time.After(f.Until):
+		return false
+	}
+
+	if f.Path != "" {
+		if _, ok := stubs.MatchPath(f.Path, e.Path); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterEntries Entries matching filter, only last Limit ones if limit is set
+func filterEntries(entries []Entry, f Filter) []Entry {
+	found := make([]Entry, 0)
+	for i := range entries {
+		if f.Matches(&entries[i]) {
+			found = append(found, entries[i])
+		}
+	}
+	if f.Limit > 0 && len(found) > f.Limit {
+		found = found[len(found)-f.Limit:]
+	}
+
+	return found
+}
@@ -0,0 +1,75 @@
+package journal
+
+import (
+	"net/http"
+	"sync"
+)
+
+// feedBuffer Exchanges queued for subscriber, exchanges are dropped for subscriber which can`t keep up
+const feedBuffer = 64
+
+// Response Response sent for journal request, body is cut to journal max body size
+type Response struct {
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body,omitempty"`
+	BodyEncoding  string      `json:"bodyEncoding,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// Exchange Recorded request with response sent for it
+type Exchange struct {
+	Entry
+	Response Response `json:"response"`
+}
+
+// Feed Live exchanges broadcast to subscribers of router instance
+type Feed struct {
+	mu          sync.Mutex
+	subscribers map[chan Exchange]Filter
+}
+
+// NewFeed Feed without subscribers
+func NewFeed() *Feed {
+	return &Feed{subscribers: make(map[chan Exchange]Filter)}
+}
+
+// Subscribe Receive exchanges matching filter until returned cancel func is called
+func (f *Feed) Subscribe(filter Filter) (<-chan Exchange, func()) {
+	ch := make(chan Exchange, feedBuffer)
+
+	f.mu.Lock()
+	f.subscribers[ch] = filter
+	f.mu.Unlock()
+
+	cancel := func() {
+		f.mu.Lock()
+		delete(f.subscribers, ch)
+		f.mu.Unlock()
+	}
+
+	return ch, cancel
+}
+
+// Active Check anybody is subscribed, responses need not be captured otherwise
+func (f *Feed) Active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.subscribers) > 0
+}
+
+// Publish Send exchange to subscribers which filters it matches without waiting for slow ones
+func (f *Feed) Publish(e Exchange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch, filter := range f.subscribers {
+		if !filter.Matches(&e.Entry) {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
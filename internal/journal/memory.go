@@ -0,0 +1,89 @@
+package journal
+
+import (
+	"github.com/overdone/stubrouter/internal/config"
+	"sync"
+)
+
+// MemoryJournal Journal kept in ring buffer of Size entries
+type MemoryJournal struct {
+	Size int
+
+	mu        sync.Mutex
+	entries   []Entry
+	next      int
+	lastID    int64
+	unmatched map[string][]Entry
+}
+
+// InitJournal Allocate ring buffer
+func (j *MemoryJournal) InitJournal(cfg *config.StubRouterConfig) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = make([]Entry, 0, j.Size)
+	j.next = 0
+	j.unmatched = make(map[string][]Entry)
+
+	return nil
+}
+
+// Record Add entry replacing the oldest one if buffer is full
+func (j *MemoryJournal) Record(e *Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastID++
+	e.ID = j.lastID
+
+	if e.Unmatched {
+		unmatched := append(j.unmatched[e.Target], *e)
+		if len(unmatched) > j.Size {
+			unmatched = unmatched[len(unmatched)-j.Size:]
+		}
+		j.unmatched[e.Target] = unmatched
+	}
+
+	if len(j.entries) < j.Size {
+		j.entries = append(j.entries, *e)
+		return nil
+	}
+	if j.Size > 0 {
+		j.entries[j.next] = *e
+		j.next = (j.next + 1) % j.Size
+	}
+
+	return nil
+}
+
+// Entries Matching entries from the oldest to the newest
+func (j *MemoryJournal) Entries(f Filter) ([]Entry, error) {
+	j.mu.Lock()
+	ordered := make([]Entry, 0, len(j.entries))
+	ordered = append(ordered, j.entries[j.next:]...)
+	ordered = append(ordered, j.entries[:j.next]...)
+	j.mu.Unlock()
+
+	return filterEntries(ordered, f), nil
+}
+
+// Unmatched Matching unmatched entries of target from the oldest to the newest
+func (j *MemoryJournal) Unmatched(target string, f Filter) ([]Entry, error) {
+	j.mu.Lock()
+	entries := append([]Entry(nil), j.unmatched[target]...)
+	j.mu.Unlock()
+
+	return filterEntries(entries, f), nil
+}
+
+// Reset Remove all entries
+func (j *MemoryJournal) Reset() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = j.entries[:0]
+	j.next = 0
+	j.unmatched = make(map[string][]Entry)
+
+	return nil
+}
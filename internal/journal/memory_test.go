@@ -0,0 +1,96 @@
+package journal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newMemoryJournal(t *testing.T, size int) *MemoryJournal {
+	t.Helper()
+	j := &MemoryJournal{Size: size}
+	if err := j.InitJournal(nil); err != nil {
+		t.Fatalf("InitJournal error: %s", err)
+	}
+	return j
+}
+
+func record(t *testing.T, j Journal, entries ...Entry) {
+	t.Helper()
+	for i := range entries {
+		if err := j.Record(&entries[i]); err != nil {
+			t.Fatalf("Record error: %s", err)
+		}
+	}
+}
+
+func ids(entries []Entry) []int64 {
+	result := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e.ID)
+	}
+	return result
+}
+
+func TestMemoryJournalEntries(t *testing.T) {
+	j := newMemoryJournal(t, 3)
+	record(t, j,
+		Entry{Target: "/a", Method: "GET", Path: "/x"},
+		Entry{Target: "/a", Method: "POST", Path: "/x"},
+		Entry{Target: "/b", Method: "GET", Path: "/y"},
+		Entry{Target: "/a", Method: "GET", Path: "/users/1"},
+	)
+
+	all, _ := j.Entries(Filter{})
+	if got := ids(all); !reflect.DeepEqual(got, []int64{2, 3, 4}) {
+		t.Errorf("entries %v, want the last 3", got)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"target", Filter{Target: "/a"}, []int64{2, 4}},
+		{"method", Filter{Method: "GET"}, []int64{3, 4}},
+		{"path pattern", Filter{Path: "/users/{id}"}, []int64{4}},
+		{"limit", Filter{Limit: 1}, []int64{4}},
+	}
+	for _, tt := range tests {
+		found, _ := j.Entries(tt.filter)
+		if got := ids(found); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: entries %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryJournalUnmatched(t *testing.T) {
+	j := newMemoryJournal(t, 2)
+	record(t, j,
+		Entry{Target: "/a", Path: "/1", Unmatched: true},
+		Entry{Target: "/b", Path: "/2", Unmatched: true},
+		Entry{Target: "/a", Path: "/3", Unmatched: true},
+		Entry{Target: "/a", Path: "/4", Stubbed: true},
+		Entry{Target: "/a", Path: "/5", Unmatched: true},
+	)
+
+	// Unmatched requests of /b are kept though other requests pushed them out of journal
+	if found, _ := j.Entries(Filter{Target: "/b"}); len(found) != 0 {
+		t.Errorf("journal entries of /b %v, want evicted", ids(found))
+	}
+	if found, _ := j.Unmatched("/b", Filter{}); !reflect.DeepEqual(ids(found), []int64{2}) {
+		t.Errorf("unmatched of /b %v, want [2]", ids(found))
+	}
+	if found, _ := j.Unmatched("/a", Filter{}); !reflect.DeepEqual(ids(found), []int64{3, 5}) {
+		t.Errorf("unmatched of /a %v, want the last 2", ids(found))
+	}
+	if found, _ := j.Unmatched("/a", Filter{Path: "/5"}); !reflect.DeepEqual(ids(found), []int64{5}) {
+		t.Errorf("filtered unmatched of /a %v, want [5]", ids(found))
+	}
+
+	if err := j.Reset(); err != nil {
+		t.Fatalf("Reset error: %s", err)
+	}
+	if found, _ := j.Unmatched("/a", Filter{}); len(found) != 0 {
+		t.Errorf("unmatched after reset %v", ids(found))
+	}
+}
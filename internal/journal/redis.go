@@ -0,0 +1,110 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"github.com/overdone/stubrouter/internal/config"
+)
+
+// Keys are prefixed to not clash with stub keys and other data in shared Redis
+const (
+	redisEntriesKey = "stubrouter:journal"
+	redisSeqKey     = "stubrouter:journal:seq"
+	// redisUnmatchedKey Set of targets with unmatched entries, entries of target are in list under key with target suffix
+	redisUnmatchedKey = "stubrouter:journal:unmatched"
+)
+
+// RedisJournal Journal kept in Redis list of Size entries, shared by router instances
+type RedisJournal struct {
+	ConnString string
+	Size       int
+
+	client *redis.Client
+}
+
+// InitJournal Connect to Redis
+func (j *RedisJournal) InitJournal(cfg *config.StubRouterConfig) error {
+	opts, err := redis.ParseURL(j.ConnString)
+	if err != nil {
+		return fmt.Errorf("invalid journal redis path")
+	}
+
+	j.client = redis.NewClient(opts)
+	return nil
+}
+
+// Record Append entry and trim list to journal size
+func (j *RedisJournal) Record(e *Entry) error {
+	ctx := context.Background()
+
+	id, err := j.client.Incr(ctx, redisSeqKey).Result()
+	if err != nil {
+		return err
+	}
+	e.ID = id
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, redisEntriesKey, data)
+		pipe.LTrim(ctx, redisEntriesKey, int64(-j.Size), -1)
+		if e.Unmatched {
+			key := redisUnmatchedKey + ":" + e.Target
+			pipe.SAdd(ctx, redisUnmatchedKey, e.Target)
+			pipe.RPush(ctx, key, data)
+			pipe.LTrim(ctx, key, int64(-j.Size), -1)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// Entries Matching entries from the oldest to the newest
+func (j *RedisJournal) Entries(f Filter) ([]Entry, error) {
+	return j.listEntries(redisEntriesKey, f)
+}
+
+// Unmatched Matching unmatched entries of target from the oldest to the newest
+func (j *RedisJournal) Unmatched(target string, f Filter) ([]Entry, error) {
+	return j.listEntries(redisUnmatchedKey+":"+target, f)
+}
+
+func (j *RedisJournal) listEntries(key string, f Filter) ([]Entry, error) {
+	ctx := context.Background()
+	values, err := j.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(values))
+	for _, v := range values {
+		var e Entry
+		if err = json.Unmarshal([]byte(v), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+
+	return filterEntries(entries, f), nil
+}
+
+// Reset Remove all entries
+func (j *RedisJournal) Reset() error {
+	ctx := context.Background()
+	targets, err := j.client.SMembers(ctx, redisUnmatchedKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{redisEntriesKey, redisUnmatchedKey}
+	for _, target := range targets {
+		keys = append(keys, redisUnmatchedKey+":"+target)
+	}
+
+	return j.client.Del(ctx, keys...).Err()
+}
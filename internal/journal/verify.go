@@ -0,0 +1,142 @@
+package journal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"sort"
+)
+
+// maxNearMisses Number of closest not matching requests reported for failed verification
+const maxNearMisses = 5
+
+// Verification Expectation on journal requests: count of requests to target matching method, path pattern
+// and conditions received in time range. Exactly Count requests are expected if it is set,
+// at least AtLeast and at most AtMost otherwise, at least one if nothing is set
+type Verification struct {
+	Target  string              `json:"target"`
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Match   *stubs.RequestMatch `json:"match"`
+	Since   string              `json:"since"`
+	Until   string              `json:"until"`
+	Count   *int                `json:"count"`
+	AtLeast *int                `json:"atLeast"`
+	AtMost  *int                `json:"atMost"`
+}
+
+// NearMiss Request which does not match verification with reasons
+type NearMiss struct {
+	Request    Entry    `json:"request"`
+	Mismatches []string `json:"mismatches"`
+}
+
+// VerifyResult Verification outcome, near misses are reported if verification failed
+type VerifyResult struct {
+	OK         bool       `json:"ok"`
+	Count      int        `json:"count"`
+	Expected   string     `json:"expected"`
+	Requests   []int64    `json:"requests"`
+	NearMisses []NearMiss `json:"nearMisses,omitempty"`
+}
+
+// RequestData Entry request in form stubs are matched against
+func (e *Entry) RequestData() *stubs.RequestData {
+	body := []byte(e.Body)
+	if e.BodyEncoding == stubs.EncodingBase64 {
+		body, _ = base64.StdEncoding.DecodeString(e.Body)
+	}
+
+	return &stubs.RequestData{Method: e.Method, Path: e.Path, Query: e.Query, Header: e.Headers, Body: body}
+}
+
+// Validate Check verification params
+func (v Verification) Validate() error {
+	if v.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if err := stubs.ValidatePath(v.Path); err != nil {
+		return fmt.Errorf("invalid path pattern: %s", err)
+	}
+	for _, n := range []*int{v.Count, v.AtLeast, v.AtMost} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("expected count must not be negative")
+		}
+	}
+
+	return v.Match.Validate()
+}
+
+// expectation Check count satisfies expected count and describe expectation
+func (v Verification) expectation(count int) (bool, string) {
+	switch {
+	case v.Count != nil:
+		return count == *v.Count, fmt.Sprintf("exactly %d", *v.Count)
+	case v.AtLeast != nil && v.AtMost != nil:
+		return count >= *v.AtLeast && count <= *v.AtMost, fmt.Sprintf("from %d to %d", *v.AtLeast, *v.AtMost)
+	case v.AtMost != nil:
+		return count <= *v.AtMost, fmt.Sprintf("at most %d", *v.AtMost)
+	case v.AtLeast != nil:
+		return count >= *v.AtLeast, fmt.Sprintf("at least %d", *v.AtLeast)
+	default:
+		return count >= 1, "at least 1"
+	}
+}
+
+// Verify Count journal requests matching verification. Near misses are requests of the same target
+// and time range with the fewest mismatches, closer paths first
+func Verify(j Journal, v Verification) (*VerifyResult, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	f := Filter{Target: v.Target}
+	var err error
+	if v.Since != "" {
+		if f.Since, err = parseTime(v.Since); err != nil {
+			return nil, fmt.Errorf("invalid since time %s", v.Since)
+		}
+	}
+	if v.Until != "" {
+		if f.Until, err = parseTime(v.Until); err != nil {
+			return nil, fmt.Errorf("invalid until time %s", v.Until)
+		}
+	}
+
+	entries, err := j.Entries(f)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := stubs.RequestPattern{Method: v.Method, Path: v.Path, Match: v.Match}
+	result := &VerifyResult{Requests: make([]int64, 0)}
+	var misses []NearMiss
+	for _, e := range entries {
+		reasons := pattern.Mismatches(e.RequestData())
+		if len(reasons) == 0 {
+			result.Requests = append(result.Requests, e.ID)
+		} else {
+			misses = append(misses, NearMiss{Request: e, Mismatches: reasons})
+		}
+	}
+
+	result.Count = len(result.Requests)
+	result.OK, result.Expected = v.expectation(result.Count)
+	if result.OK {
+		return result, nil
+	}
+
+	sort.SliceStable(misses, func(i, j int) bool {
+		a, b := misses[i], misses[j]
+		if len(a.Mismatches) != len(b.Mismatches) {
+			return len(a.Mismatches) < len(b.Mismatches)
+		}
+		return stubs.PathDistance(v.Path, a.Request.Path) < stubs.PathDistance(v.Path, b.Request.Path)
+	})
+	if len(misses) > maxNearMisses {
+		misses = misses[:maxNearMisses]
+	}
+	result.NearMisses = misses
+
+	return result, nil
+}
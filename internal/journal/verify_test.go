@@ -0,0 +1,122 @@
+package journal
+
+import (
+	"github.com/overdone/stubrouter/internal/stubs"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func intp(n int) *int {
+	return &n
+}
+
+func TestVerify(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	j := newMemoryJournal(t, 20)
+	record(t, j,
+		Entry{Time: start, Target: "/app1", Method: "POST", Path: "/orders", Body: `{"sku": "A1"}`},
+		Entry{Time: start.Add(time.Minute), Target: "/app1", Method: "POST", Path: "/orders", Body: `{"sku": "B2"}`},
+		Entry{Time: start.Add(2 * time.Minute), Target: "/app1", Method: "POST", Path: "/orders", Body: `{"sku": "A1"}`},
+		Entry{Time: start.Add(3 * time.Minute), Target: "/app1", Method: "GET", Path: "/orders"},
+		Entry{Time: start.Add(4 * time.Minute), Target: "/app2", Method: "POST", Path: "/orders", Body: `{"sku": "A1"}`},
+	)
+
+	skuA1 := &stubs.RequestMatch{Body: []stubs.BodyMatcher{{JsonPath: "$.sku", Value: &stubs.ValueMatcher{Equals: "A1"}}}}
+	tests := []struct {
+		name     string
+		v        Verification
+		ok       bool
+		count    int
+		expected string
+	}{
+		{"default at least one", Verification{Target: "/app1", Method: "POST", Path: "/orders"}, true, 3, "at least 1"},
+		{"exact count", Verification{Target: "/app1", Method: "POST", Path: "/orders", Match: skuA1, Count: intp(2)}, true, 2, "exactly 2"},
+		{"wrong exact count", Verification{Target: "/app1", Method: "POST", Path: "/orders", Match: skuA1, Count: intp(1)}, false, 2, "exactly 1"},
+		{"all targets", Verification{Method: "POST", Path: "/orders", Match: skuA1, Count: intp(3)}, true, 3, "exactly 3"},
+		{"at least", Verification{Target: "/app1", Path: "/orders", AtLeast: intp(5)}, false, 4, "at least 5"},
+		{"at most", Verification{Target: "/app1", Method: "GET", Path: "/orders", AtMost: intp(0)}, false, 1, "at most 0"},
+		{"range", Verification{Target: "/app1", Path: "/orders", AtLeast: intp(1), AtMost: intp(4)}, true, 4, "from 1 to 4"},
+		{"count wins over range", Verification{Target: "/app1", Path: "/orders", Count: intp(4), AtMost: intp(1)}, true, 4, "exactly 4"},
+		{"since", Verification{Target: "/app1", Method: "POST", Path: "/orders", Since: start.Add(time.Minute).Format(time.RFC3339)}, true, 2, "at least 1"},
+		{"until in unix ms", Verification{Target: "/app1", Method: "POST", Path: "/orders", Until: "1704103200000"}, true, 1, "at least 1"},
+		{"path pattern", Verification{Target: "/app1", Method: "GET", Path: "/{resource}", Count: intp(1)}, true, 1, "exactly 1"},
+		{"none received", Verification{Target: "/app1", Method: "DELETE", Path: "/orders"}, false, 0, "at least 1"},
+	}
+
+	for _, tt := range tests {
+		result, err := Verify(j, tt.v)
+		if err != nil {
+			t.Errorf("%s: Verify error: %s", tt.name, err)
+			continue
+		}
+		if result.OK != tt.ok || result.Count != tt.count || result.Expected != tt.expected || len(result.Requests) != tt.count {
+			t.Errorf("%s: result %+v, want ok %t, count %d, expected %q", tt.name, result, tt.ok, tt.count, tt.expected)
+		}
+		if result.OK && len(result.NearMisses) != 0 {
+			t.Errorf("%s: near misses reported for passed verification", tt.name)
+		}
+	}
+}
+
+func TestVerifyNearMisses(t *testing.T) {
+	j := newMemoryJournal(t, 20)
+	record(t, j,
+		Entry{Target: "/app1", Method: "GET", Path: "/users"},
+		Entry{Target: "/app1", Method: "POST", Path: "/order"},
+		Entry{Target: "/app1", Method: "PUT", Path: "/orders", Headers: http.Header{"X-Api-Version": {"2"}}},
+		Entry{Target: "/app1", Method: "POST", Path: "/orders"},
+		Entry{Target: "/app1", Method: "GET", Path: "/orders/1"},
+		Entry{Target: "/app1", Method: "GET", Path: "/a"},
+		Entry{Target: "/app1", Method: "GET", Path: "/b"},
+		Entry{Target: "/app2", Method: "POST", Path: "/orders", Headers: http.Header{"X-Api-Version": {"1"}}},
+	)
+
+	result, err := Verify(j, Verification{Target: "/app1", Method: "POST", Path: "/orders", Match: &stubs.RequestMatch{
+		Headers: map[string]stubs.ValueMatcher{"X-Api-Version": {Equals: "1"}},
+	}})
+	if err != nil {
+		t.Fatalf("Verify error: %s", err)
+	}
+	if result.OK || result.Count != 0 {
+		t.Fatalf("result %+v, want failed verification", result)
+	}
+
+	// Fewest mismatches first, closer path among requests with the same number of mismatches
+	var got []int64
+	for _, m := range result.NearMisses {
+		got = append(got, m.Request.ID)
+	}
+	if want := []int64{4, 3, 2, 5, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("near misses %v, want %v", got, want)
+	}
+	if len(result.NearMisses) > 0 && len(result.NearMisses[0].Mismatches) != 1 {
+		t.Errorf("closest near miss mismatches %v, want header one only", result.NearMisses[0].Mismatches)
+	}
+}
+
+func TestVerificationValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		v    Verification
+		ok   bool
+	}{
+		{"valid", Verification{Path: "/orders", Count: intp(0)}, true},
+		{"no path", Verification{}, false},
+		{"invalid path pattern", Verification{Path: "regex:("}, false},
+		{"negative count", Verification{Path: "/orders", AtLeast: intp(-1)}, false},
+		{"invalid match", Verification{Path: "/orders", Match: &stubs.RequestMatch{Query: map[string]stubs.ValueMatcher{"q": {Regex: "("}}}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.v.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+
+	j := newMemoryJournal(t, 1)
+	if _, err := Verify(j, Verification{Path: "/orders", Since: "yesterday"}); err == nil {
+		t.Error("Verify with invalid since time succeeded")
+	}
+}
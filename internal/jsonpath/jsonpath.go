@@ -0,0 +1,364 @@
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported syntax subset:
+//   $            root
+//   .name        child by name, ['name'] in bracket notation
+//   .*, [*]      all children
+//   ..name       recursive descent
+//   [n]          array index, negative index counts from the end
+//   [?(@.a.b)]   filter by field existence
+//   [?(@.a op v)] filter by field comparison, op is one of == != < <= > >=,
+//                value is number, quoted string, true, false or null
+
+type stepKind int
+
+const (
+	stepChild stepKind = iota
+	stepWildcard
+	stepRecursive
+	stepIndex
+	stepFilter
+)
+
+type filter struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+type step struct {
+	kind   stepKind
+	name   string
+	index  int
+	filter *filter
+}
+
+// Path Compiled JSONPath expression
+type Path struct {
+	steps []step
+}
+
+// Compile Parse JSONPath expression
+func Compile(expr string) (*Path, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath %q must start with $", expr)
+	}
+
+	p := &Path{}
+	rest := expr[1:]
+	for rest != "" {
+		var (
+			s   step
+			err error
+		)
+
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			s.kind = stepRecursive
+			s.name, rest = readName(rest[2:])
+			if s.name == "" {
+				return nil, fmt.Errorf("jsonpath %q: name expected after ..", expr)
+			}
+		case strings.HasPrefix(rest, ".*"):
+			s.kind = stepWildcard
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			s.kind = stepChild
+			s.name, rest = readName(rest[1:])
+			if s.name == "" {
+				return nil, fmt.Errorf("jsonpath %q: name expected after .", expr)
+			}
+		case strings.HasPrefix(rest, "["):
+			s, rest, err = readBracket(rest)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: %s", expr, err)
+			}
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", expr, rest)
+		}
+
+		p.steps = append(p.steps, s)
+	}
+
+	return p, nil
+}
+
+// Eval Compile expression and evaluate it against decoded JSON document
+func Eval(expr string, doc interface{}) ([]interface{}, error) {
+	p, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Eval(doc), nil
+}
+
+// Eval Select values from decoded JSON document
+func (p *Path) Eval(doc interface{}) []interface{} {
+	nodes := []interface{}{doc}
+	for _, s := range p.steps {
+		var next []interface{}
+		for _, n := range nodes {
+			next = append(next, s.apply(n)...)
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+func readName(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+
+	return s[:i], s[i:]
+}
+
+func readBracket(s string) (step, string, error) {
+	end := matchingBracket(s)
+	if end < 0 {
+		return step{}, "", fmt.Errorf("unclosed [")
+	}
+
+	body, rest := strings.TrimSpace(s[1:end]), s[end+1:]
+	switch {
+	case body == "*":
+		return step{kind: stepWildcard}, rest, nil
+	case strings.HasPrefix(body, "?(") && strings.HasSuffix(body, ")"):
+		f, err := parseFilter(strings.TrimSpace(body[2 : len(body)-1]))
+		return step{kind: stepFilter, filter: f}, rest, err
+	case strings.HasPrefix(body, "'") || strings.HasPrefix(body, `"`):
+		name, err := unquote(body)
+		return step{kind: stepChild, name: name}, rest, err
+	default:
+		idx, err := strconv.Atoi(body)
+		if err != nil {
+			return step{}, "", fmt.Errorf("invalid index %q", body)
+		}
+		return step{kind: stepIndex, index: idx}, rest, nil
+	}
+}
+
+// matchingBracket Position of bracket closing the first one, quoted brackets are skipped
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+
+	return s[1 : len(s)-1], nil
+}
+
+func parseFilter(expr string) (*filter, error) {
+	f := &filter{}
+	left := expr
+
+	// First operator in expression, longer operator wins at same position
+	pos := -1
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if i := strings.Index(expr, op); i > 0 && (pos < 0 || i < pos) {
+			pos, f.op = i, op
+		}
+	}
+
+	if pos > 0 {
+		left = strings.TrimSpace(expr[:pos])
+		v, err := parseLiteral(strings.TrimSpace(expr[pos+len(f.op):]))
+		if err != nil {
+			return nil, err
+		}
+		f.value = v
+	}
+
+	if left != "@" && !strings.HasPrefix(left, "@.") {
+		return nil, fmt.Errorf("filter %q must refer to @", expr)
+	}
+	if left != "@" {
+		f.path = strings.Split(left[2:], ".")
+	}
+
+	return f, nil
+}
+
+func parseLiteral(s string) (interface{}, error) {
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`) {
+		return unquote(s)
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter value %s", s)
+	}
+
+	return n, nil
+}
+
+func (s step) apply(node interface{}) []interface{} {
+	switch s.kind {
+	case stepChild:
+		if m, ok := node.(map[string]interface{}); ok {
+			if v, ok := m[s.name]; ok {
+				return []interface{}{v}
+			}
+		}
+	case stepWildcard:
+		return children(node)
+	case stepRecursive:
+		var found []interface{}
+		if m, ok := node.(map[string]interface{}); ok {
+			if v, ok := m[s.name]; ok {
+				found = append(found, v)
+			}
+		}
+		for _, c := range children(node) {
+			found = append(found, s.apply(c)...)
+		}
+		return found
+	case stepIndex:
+		if a, ok := node.([]interface{}); ok {
+			idx := s.index
+			if idx < 0 {
+				idx += len(a)
+			}
+			if idx >= 0 && idx < len(a) {
+				return []interface{}{a[idx]}
+			}
+		}
+	case stepFilter:
+		var found []interface{}
+		for _, c := range children(node) {
+			if s.filter.matches(c) {
+				found = append(found, c)
+			}
+		}
+		return found
+	}
+
+	return nil
+}
+
+// children Object values in key order or array items
+func children(node interface{}) []interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, 0, len(n))
+		for _, k := range keys {
+			values = append(values, n[k])
+		}
+		return values
+	case []interface{}:
+		return n
+	}
+
+	return nil
+}
+
+func (f *filter) matches(node interface{}) bool {
+	v := node
+	for _, name := range f.path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = m[name]; !ok {
+			return false
+		}
+	}
+
+	if f.op == "" {
+		return true
+	}
+
+	if a, ok := v.(float64); ok {
+		if b, ok := f.value.(float64); ok {
+			switch f.op {
+			case "==":
+				return a == b
+			case "!=":
+				return a != b
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			case ">":
+				return a > b
+			case ">=":
+				return a >= b
+			}
+		}
+	}
+
+	if a, ok := v.(string); ok {
+		if b, ok := f.value.(string); ok {
+			switch f.op {
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			case ">":
+				return a > b
+			case ">=":
+				return a >= b
+			}
+		}
+	}
+
+	switch f.op {
+	case "==":
+		return reflect.DeepEqual(v, f.value)
+	case "!=":
+		return !reflect.DeepEqual(v, f.value)
+	}
+
+	return false
+}
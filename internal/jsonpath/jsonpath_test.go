@@ -0,0 +1,104 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDoc = `{
+  "store": {
+    "name": "corner",
+    "open": true,
+    "books": [
+      {"title": "A", "price": 8.5, "tags": ["x"], "author": {"name": "Ann"}},
+      {"title": "B", "price": 12, "isbn": "1-2"},
+      {"title": "C", "price": 20, "author": {"name": "Bob"}, "note": null}
+    ],
+    "odd.key": 1,
+    "bracket]key": 2
+  }
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("bad test document: %s", err)
+	}
+	return doc
+}
+
+func TestEval(t *testing.T) {
+	doc := decode(t, testDoc)
+
+	tests := []struct {
+		name string
+		expr string
+		want []interface{}
+	}{
+		{"root", "$.store.name", []interface{}{"corner"}},
+		{"bracket name", "$['store']['name']", []interface{}{"corner"}},
+		{"double quoted name", `$["store"]["open"]`, []interface{}{true}},
+		{"dot in quoted name", "$.store['odd.key']", []interface{}{1.0}},
+		{"bracket in quoted name", "$.store['bracket]key']", []interface{}{2.0}},
+		{"index", "$.store.books[1].title", []interface{}{"B"}},
+		{"negative index", "$.store.books[-1].title", []interface{}{"C"}},
+		{"index out of range", "$.store.books[5].title", nil},
+		{"wildcard", "$.store.books[*].title", []interface{}{"A", "B", "C"}},
+		{"dot wildcard", "$.store.books.*.price", []interface{}{8.5, 12.0, 20.0}},
+		{"recursive", "$..name", []interface{}{"corner", "Ann", "Bob"}},
+		{"filter exists", "$.store.books[?(@.isbn)].title", []interface{}{"B"}},
+		{"filter nested exists", "$.store.books[?(@.author.name)].title", []interface{}{"A", "C"}},
+		{"filter number", "$.store.books[?(@.price > 10)].title", []interface{}{"B", "C"}},
+		{"filter number le", "$.store.books[?(@.price <= 12)].title", []interface{}{"A", "B"}},
+		{"filter string", "$.store.books[?(@.title == 'C')].price", []interface{}{20.0}},
+		{"filter not equal", "$.store.books[?(@.title != 'C')].title", []interface{}{"A", "B"}},
+		{"filter null", "$.store.books[?(@.note == null)].title", []interface{}{"C"}},
+		{"filter nested", "$.store.books[?(@.author.name == 'Bob')].title", []interface{}{"C"}},
+		{"filter string compare", "$.store.books[?(@.title >= 'B')].title", []interface{}{"B", "C"}},
+		{"filter type mismatch", "$.store.books[?(@.price == '8.5')].title", nil},
+		{"missing", "$.store.missing", nil},
+		{"child of scalar", "$.store.name.first", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Eval(tt.expr, doc)
+			if err != nil {
+				t.Fatalf("Eval(%q) error: %s", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"store.name", "must start with $"},
+		{"$.", "name expected after ."},
+		{"$..", "name expected after .."},
+		{"$.a[1", "unclosed ["},
+		{"$.a['b]", "unclosed ["},
+		{"$.a[x]", "invalid index"},
+		{"$.a['b'x]", "invalid string"},
+		{"$.a[?(name == 1)]", "must refer to @"},
+		{"$.a[?(@.b == c)]", "invalid filter value"},
+		{"$a", "unexpected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Compile(%q) = %v, want error containing %q", tt.expr, err, tt.err)
+			}
+		})
+	}
+}
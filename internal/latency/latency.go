@@ -0,0 +1,105 @@
+package latency
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// Fixed Always Value ms
+	Fixed = "fixed"
+	// Uniform Uniformly distributed in [Min, Max] ms
+	Uniform = "uniform"
+	// Normal Normally distributed with Mean and StdDev ms
+	Normal = "normal"
+	// LogNormal Log-normally distributed with Median ms and Sigma shape, gives long tail of slow responses
+	LogNormal = "lognormal"
+)
+
+// Profile Latency distribution. Sampled value is clamped to [Min, Max] range if Max is set
+type Profile struct {
+	Type   string  `yaml:"type" json:"type"`
+	Value  int     `yaml:"value,omitempty" json:"value,omitempty"`
+	Min    int     `yaml:"min,omitempty" json:"min,omitempty"`
+	Max    int     `yaml:"max,omitempty" json:"max,omitempty"`
+	Mean   float64 `yaml:"mean,omitempty" json:"mean,omitempty"`
+	StdDev float64 `yaml:"stddev,omitempty" json:"stddev,omitempty"`
+	Median float64 `yaml:"median,omitempty" json:"median,omitempty"`
+	Sigma  float64 `yaml:"sigma,omitempty" json:"sigma,omitempty"`
+}
+
+// Validate Check distribution params
+func (p *Profile) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	switch p.Type {
+	case Fixed:
+		if p.Value < 0 {
+			return fmt.Errorf("fixed latency must not be negative")
+		}
+	case Uniform:
+		if p.Min < 0 || p.Max < p.Min {
+			return fmt.Errorf("uniform latency requires 0 <= min <= max")
+		}
+	case Normal:
+		if p.StdDev < 0 {
+			return fmt.Errorf("normal latency stddev must not be negative")
+		}
+	case LogNormal:
+		if p.Median <= 0 || p.Sigma < 0 {
+			return fmt.Errorf("lognormal latency requires positive median and non-negative sigma")
+		}
+	default:
+		return fmt.Errorf("unknown latency type %q", p.Type)
+	}
+
+	return nil
+}
+
+// Sample Random latency value from distribution, zero for nil profile
+func (p *Profile) Sample() time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	var ms float64
+	switch p.Type {
+	case Fixed:
+		return time.Duration(p.Value) * time.Millisecond
+	case Uniform:
+		ms = float64(p.Min) + rand.Float64()*float64(p.Max-p.Min)
+	case Normal:
+		ms = p.Mean + rand.NormFloat64()*p.StdDev
+	case LogNormal:
+		ms = p.Median * math.Exp(rand.NormFloat64()*p.Sigma)
+	}
+
+	ms = math.Max(ms, float64(p.Min))
+	if p.Max > 0 {
+		ms = math.Min(ms, float64(p.Max))
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// Sleep Wait for duration or until context is done
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
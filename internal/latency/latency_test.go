@@ -0,0 +1,79 @@
+package latency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *Profile
+		ok      bool
+	}{
+		{"no profile", nil, true},
+		{"fixed", &Profile{Type: Fixed, Value: 100}, true},
+		{"negative fixed", &Profile{Type: Fixed, Value: -1}, false},
+		{"uniform", &Profile{Type: Uniform, Min: 10, Max: 20}, true},
+		{"uniform max below min", &Profile{Type: Uniform, Min: 20, Max: 10}, false},
+		{"normal", &Profile{Type: Normal, Mean: 100, StdDev: 10}, true},
+		{"normal negative stddev", &Profile{Type: Normal, Mean: 100, StdDev: -1}, false},
+		{"lognormal", &Profile{Type: LogNormal, Median: 100, Sigma: 0.5}, true},
+		{"lognormal without median", &Profile{Type: LogNormal, Sigma: 0.5}, false},
+		{"unknown type", &Profile{Type: "pareto"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.profile.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		profile *Profile
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"no profile", nil, 0, 0},
+		{"fixed", &Profile{Type: Fixed, Value: 100}, 100 * ms, 100 * ms},
+		{"uniform", &Profile{Type: Uniform, Min: 10, Max: 20}, 10 * ms, 20 * ms},
+		{"normal without spread", &Profile{Type: Normal, Mean: 50}, 50 * ms, 50 * ms},
+		{"normal clamped", &Profile{Type: Normal, Mean: 100, StdDev: 1000, Min: 80, Max: 120}, 80 * ms, 120 * ms},
+		{"normal never negative", &Profile{Type: Normal, Mean: -100, StdDev: 10}, 0, 0},
+		{"lognormal without spread", &Profile{Type: LogNormal, Median: 30}, 30 * ms, 30 * ms},
+		{"lognormal clamped", &Profile{Type: LogNormal, Median: 100, Sigma: 3, Min: 50, Max: 200}, 50 * ms, 200 * ms},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 1000; i++ {
+			if d := tt.profile.Sample(); d < tt.min || d > tt.max {
+				t.Errorf("%s: sample %s, want in [%s, %s]", tt.name, d, tt.min, tt.max)
+				break
+			}
+		}
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if err := Sleep(context.Background(), 5*time.Millisecond); err != nil {
+		t.Errorf("Sleep error: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := Sleep(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("Sleep with canceled context error %v, want canceled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Sleep with canceled context took %s", time.Since(start))
+	}
+	if err := Sleep(ctx, 0); err != context.Canceled {
+		t.Errorf("zero Sleep with canceled context error %v, want canceled", err)
+	}
+}
@@ -0,0 +1,462 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errCycle Schema refers to itself, recursive values are left out of synthesized data
+var errCycle = errors.New("schema cycle")
+
+// Limits of synthesized data size, larger minLength and minItems are clamped to them
+const (
+	maxSynthesizedLength = 1024
+	maxSynthesizedItems  = 16
+)
+
+// MockResponse Operation response built from spec examples or synthesized from response schema
+type MockResponse struct {
+	Method      string
+	Path        string
+	Status      int
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+}
+
+// MockResponses Response for every document operation. Operation gets its lowest success response,
+// default response if it has no success ones. Operations without both are left out, operations
+// which response can`t be built are left out with error
+func (d *Document) MockResponses() ([]MockResponse, []error) {
+	var mocks []MockResponse
+	var errs []error
+	for _, path := range d.SortedPaths() {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+
+		ops := item.Operations()
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			if !hasSuccessResponse(ops[method]) {
+				continue
+			}
+			mock, err := d.mockResponse(ops[method])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %s", method, path, err))
+				continue
+			}
+			mock.Method = method
+			mock.Path = d.BasePath() + path
+			mocks = append(mocks, mock)
+		}
+	}
+
+	return mocks, errs
+}
+
+// StatusCode Response status for responses map key, ranges like 2XX give first code of range
+func StatusCode(key string) (int, bool) {
+	if len(key) == 3 && strings.HasSuffix(strings.ToUpper(key), "XX") {
+		key = key[:1] + "00"
+	}
+	code, err := strconv.Atoi(key)
+	if err != nil || code < 100 || code > 599 {
+		return 0, false
+	}
+
+	return code, true
+}
+
+func hasSuccessResponse(op *Operation) bool {
+	for key := range op.Responses {
+		if code, ok := StatusCode(key); ok && code >= 200 && code < 300 || key == "default" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// successResponse Lowest success response of operation or default one
+func (d *Document) successResponse(op *Operation) (int, *Response, error) {
+	bestCode := 0
+	bestKey := ""
+	for key := range op.Responses {
+		code, ok := StatusCode(key)
+		if ok && code >= 200 && code < 300 && (bestCode == 0 || code < bestCode) {
+			bestCode, bestKey = code, key
+		}
+	}
+
+	if bestCode == 0 {
+		if _, ok := op.Responses["default"]; !ok {
+			return 0, nil, fmt.Errorf("operation has no success or default response")
+		}
+		bestCode, bestKey = 200, "default"
+	}
+
+	resp, err := d.Response(op.Responses[bestKey])
+	if err != nil {
+		return 0, nil, err
+	}
+	if resp == nil {
+		resp = &Response{}
+	}
+
+	return bestCode, resp, nil
+}
+
+// PreferredMediaType Preferred response content: application/json, other JSON type or first one in name order
+func PreferredMediaType(content map[string]*MediaType) (string, *MediaType) {
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "application/json" {
+			return name, content[name]
+		}
+	}
+	for _, name := range names {
+		if IsJSON(name) {
+			return name, content[name]
+		}
+	}
+	if len(names) > 0 {
+		return names[0], content[names[0]]
+	}
+
+	return "", nil
+}
+
+// IsJSON Check media type is JSON
+func IsJSON(mediaType string) bool {
+	mediaType = strings.TrimSpace(strings.Split(mediaType, ";")[0])
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (d *Document) mockResponse(op *Operation) (MockResponse, error) {
+	status, resp, err := d.successResponse(op)
+	if err != nil {
+		return MockResponse{}, err
+	}
+
+	mock := MockResponse{Status: status, Headers: make(map[string]string)}
+	for name, h := range resp.Headers {
+		if h, err = d.Header(h); err != nil {
+			return mock, err
+		}
+		if h == nil || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+
+		value := h.Example
+		if value == nil {
+			if value, err = d.Synthesize(h.Schema); err != nil {
+				return mock, err
+			}
+		}
+		if value != nil {
+			mock.Headers[name] = fmt.Sprint(value)
+		}
+	}
+
+	contentType, mt := PreferredMediaType(resp.Content)
+	if mt == nil {
+		return mock, nil
+	}
+	mock.ContentType = contentType
+	mock.Headers["Content-Type"] = contentType
+
+	value, err := d.exampleValue(mt)
+	if err != nil {
+		return mock, err
+	}
+	mock.Body, err = encodeBody(contentType, value)
+
+	return mock, err
+}
+
+// exampleValue Media type example, first named example or data synthesized from schema
+func (d *Document) exampleValue(mt *MediaType) (interface{}, error) {
+	if mt.Example != nil {
+		return mt.Example, nil
+	}
+
+	names := make([]string, 0, len(mt.Examples))
+	for name := range mt.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e, err := d.Example(mt.Examples[name])
+		if err != nil {
+			return nil, err
+		}
+		if e != nil && e.Value != nil {
+			return e.Value, nil
+		}
+	}
+
+	return d.Synthesize(mt.Schema)
+}
+
+// encodeBody JSON encoded value, string values of other media types are written as is
+func encodeBody(contentType string, value interface{}) ([]byte, error) {
+	if value == nil {
+		if IsJSON(contentType) {
+			return []byte("null"), nil
+		}
+		return nil, nil
+	}
+
+	if s, ok := value.(string); ok && !IsJSON(contentType) {
+		return []byte(s), nil
+	}
+
+	return json.Marshal(normalize(value))
+}
+
+// normalize Convert yaml decoded maps with non string keys to JSON encodable ones
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Synthesize Sample value for schema: schema example, default or first enum value if present,
+// otherwise value built from type and format
+func (d *Document) Synthesize(s *Schema) (interface{}, error) {
+	value, err := d.synthesize(s, nil)
+	if err == errCycle {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+// synthesize Sample value for schema with refs chain it is nested in
+func (d *Document) synthesize(s *Schema, refs []string) (interface{}, error) {
+	if s != nil && s.Ref != "" {
+		for _, ref := range refs {
+			if ref == s.Ref {
+				return nil, errCycle
+			}
+		}
+		refs = append(refs[:len(refs):len(refs)], s.Ref)
+	}
+
+	s, err := d.Schema(s)
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	switch {
+	case s.Example != nil:
+		return s.Example, nil
+	case s.Default != nil:
+		return s.Default, nil
+	case len(s.Enum) > 0:
+		return s.Enum[0], nil
+	case len(s.AllOf) > 0:
+		return d.synthesizeAllOf(s, refs)
+	case len(s.OneOf) > 0:
+		return d.synthesize(s.OneOf[0], refs)
+	case len(s.AnyOf) > 0:
+		return d.synthesize(s.AnyOf[0], refs)
+	}
+
+	if err = checkSizes(s); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.Type.Has("object") || len(s.Type) == 0 && s.Properties != nil:
+		obj := make(map[string]interface{}, len(s.Properties))
+		for name, prop := range s.Properties {
+			value, err := d.synthesize(prop, refs)
+			if err == errCycle {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = value
+		}
+		return obj, nil
+
+	case s.Type.Has("array"):
+		count := 1
+		if s.MinItems != nil && *s.MinItems > count {
+			count = *s.MinItems
+		}
+		if count > maxSynthesizedItems {
+			count = maxSynthesizedItems
+		}
+		if s.MaxItems != nil && *s.MaxItems < count {
+			count = *s.MaxItems
+		}
+		items := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			item, err := d.synthesize(s.Items, refs)
+			if err == errCycle {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case s.Type.Has("string"):
+		return synthesizeString(s), nil
+
+	case s.Type.Has("integer"):
+		return int64(synthesizeNumber(s)), nil
+
+	case s.Type.Has("number"):
+		return synthesizeNumber(s), nil
+
+	case s.Type.Has("boolean"):
+		return true, nil
+	}
+
+	return nil, nil
+}
+
+// checkSizes Length and items limits of schema can`t be negative
+func checkSizes(s *Schema) error {
+	limits := []struct {
+		name  string
+		value *int
+	}{
+		{"minLength", s.MinLength},
+		{"maxLength", s.MaxLength},
+		{"minItems", s.MinItems},
+		{"maxItems", s.MaxItems},
+	}
+	for _, l := range limits {
+		if l.value != nil && *l.value < 0 {
+			return fmt.Errorf("schema %s %d is negative", l.name, *l.value)
+		}
+	}
+
+	return nil
+}
+
+// synthesizeAllOf Merge of all subschemas values, last non object value wins
+func (d *Document) synthesizeAllOf(s *Schema, refs []string) (interface{}, error) {
+	var result interface{}
+	for _, sub := range s.AllOf {
+		value, err := d.synthesize(sub, refs)
+		if err == errCycle {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		obj, isObj := value.(map[string]interface{})
+		if !isObj {
+			if value != nil {
+				result = value
+			}
+			continue
+		}
+
+		// Values may be shared schema examples, merge them to new object
+		acc, accObj := result.(map[string]interface{})
+		if !accObj {
+			acc = make(map[string]interface{}, len(obj))
+			result = acc
+		}
+		for k, v := range obj {
+			acc[k] = v
+		}
+	}
+
+	return result, nil
+}
+
+func synthesizeString(s *Schema) string {
+	var value string
+	switch s.Format {
+	case "date-time":
+		value = "2024-01-01T00:00:00Z"
+	case "date":
+		value = "2024-01-01"
+	case "time":
+		value = "00:00:00"
+	case "email":
+		value = "user@example.com"
+	case "uuid":
+		value = "00000000-0000-4000-8000-000000000000"
+	case "uri", "url":
+		value = "https://example.com"
+	case "hostname":
+		value = "example.com"
+	case "ipv4":
+		value = "127.0.0.1"
+	case "ipv6":
+		value = "::1"
+	case "byte":
+		value = "c3RyaW5n"
+	default:
+		value = "string"
+	}
+
+	if s.MinLength != nil && len(value) < *s.MinLength {
+		length := *s.MinLength
+		if length > maxSynthesizedLength {
+			length = maxSynthesizedLength
+		}
+		value += strings.Repeat("x", length-len(value))
+	}
+	if s.MaxLength != nil && len(value) > *s.MaxLength {
+		value = value[:*s.MaxLength]
+	}
+
+	return value
+}
+
+func synthesizeNumber(s *Schema) float64 {
+	switch {
+	case s.Minimum != nil:
+		return *s.Minimum
+	case s.Maximum != nil && *s.Maximum < 0:
+		return *s.Maximum
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,174 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMockResponses(t *testing.T) {
+	doc := parseSpec(t, testSpec)
+
+	mocks, errs := doc.MockResponses()
+	if len(errs) > 0 {
+		t.Fatalf("MockResponses errors: %v", errs)
+	}
+
+	pet := `{"id":1,"name":"string","owner":{"pets":[]},"tag":"string"}`
+	want := []MockResponse{
+		{Method: "GET", Path: "/v1/pets", Status: 200, ContentType: "application/json",
+			Headers: map[string]string{"Content-Type": "application/json", "X-Total": "0"}, Body: []byte("[" + pet + "]")},
+		{Method: "POST", Path: "/v1/pets", Status: 201, ContentType: "application/json",
+			Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"id":1}`)},
+		{Method: "GET", Path: "/v1/pets/mine", Status: 200, ContentType: "text/plain",
+			Headers: map[string]string{"Content-Type": "text/plain"}, Body: []byte("mine")},
+		{Method: "GET", Path: "/v1/pets/{id}", Status: 200, ContentType: "application/json",
+			Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(pet)},
+	}
+
+	if len(mocks) != len(want) {
+		t.Fatalf("got %d mocks, want %d", len(mocks), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(mocks[i], want[i]) {
+			t.Errorf("mock %d = %+v (body %s), want %+v (body %s)", i, mocks[i], mocks[i].Body, want[i], want[i].Body)
+		}
+	}
+}
+
+func TestMockResponsesSkipBroken(t *testing.T) {
+	doc := parseSpec(t, `
+openapi: 3.1.0
+paths:
+  /a:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Missing"
+    delete:
+      responses:
+        "404":
+          description: only failures
+    put:
+      responses:
+        "200":
+          content:
+            text/plain:
+              schema:
+                type: string
+                maxLength: -1
+  /b:
+    get:
+      responses:
+        default:
+          content:
+            application/json:
+              schema:
+                type: boolean
+`)
+
+	mocks, errs := doc.MockResponses()
+	if len(errs) != 2 || !strings.Contains(errs[0].Error()+errs[1].Error(), "GET /a") || !strings.Contains(errs[0].Error()+errs[1].Error(), "PUT /a") {
+		t.Errorf("errors = %v, want ones for GET /a and PUT /a", errs)
+	}
+	if len(mocks) != 1 || mocks[0].Path != "/b" || mocks[0].Status != 200 || string(mocks[0].Body) != "true" {
+		t.Errorf("mocks = %+v, want default response of GET /b", mocks)
+	}
+}
+
+func TestSynthesize(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		want   interface{}
+	}{
+		{"example wins", &Schema{Type: Types{"string"}, Example: "ex", Default: "def"}, "ex"},
+		{"default", &Schema{Type: Types{"integer"}, Default: 5}, 5},
+		{"enum", &Schema{Type: Types{"string"}, Enum: []interface{}{"a", "b"}}, "a"},
+		{"format", &Schema{Type: Types{"string"}, Format: "uuid"}, "00000000-0000-4000-8000-000000000000"},
+		{"min length", &Schema{Type: Types{"string"}, MinLength: intPtr(8)}, "stringxx"},
+		{"max length", &Schema{Type: Types{"string"}, MaxLength: intPtr(3)}, "str"},
+		{"minimum", &Schema{Type: Types{"integer"}, Minimum: floatPtr(3)}, int64(3)},
+		{"negative maximum", &Schema{Type: Types{"number"}, Maximum: floatPtr(-2)}, -2.0},
+		{"min items", &Schema{Type: Types{"array"}, MinItems: intPtr(2), Items: &Schema{Type: Types{"boolean"}}},
+			[]interface{}{true, true}},
+		{"max items", &Schema{Type: Types{"array"}, MaxItems: intPtr(0), Items: &Schema{Type: Types{"boolean"}}},
+			[]interface{}{}},
+		{"all of", &Schema{AllOf: []*Schema{
+			{Properties: map[string]*Schema{"a": {Type: Types{"boolean"}}}},
+			{Properties: map[string]*Schema{"b": {Type: Types{"string"}, Example: "x"}}},
+		}}, map[string]interface{}{"a": true, "b": "x"}},
+		{"one of", &Schema{OneOf: []*Schema{{Type: Types{"number"}}, {Type: Types{"string"}}}}, 0.0},
+		{"no type", &Schema{}, nil},
+	}
+
+	doc := &Document{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := doc.Synthesize(tt.schema)
+			if err != nil {
+				t.Fatalf("Synthesize error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Synthesize = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynthesizeLimits(t *testing.T) {
+	huge := 1 << 30
+	doc := &Document{}
+
+	s, err := doc.Synthesize(&Schema{Type: Types{"string"}, MinLength: &huge})
+	if err != nil || len(s.(string)) != maxSynthesizedLength {
+		t.Errorf("string of %d characters, error %v, want %d characters", len(s.(string)), err, maxSynthesizedLength)
+	}
+
+	a, err := doc.Synthesize(&Schema{Type: Types{"array"}, MinItems: &huge, Items: &Schema{Type: Types{"integer"}}})
+	if err != nil || len(a.([]interface{})) != maxSynthesizedItems {
+		t.Errorf("array of %d items, error %v, want %d items", len(a.([]interface{})), err, maxSynthesizedItems)
+	}
+
+	negative := -1
+	for _, schema := range []*Schema{
+		{Type: Types{"string"}, MaxLength: &negative},
+		{Type: Types{"string"}, MinLength: &negative},
+		{Type: Types{"array"}, MaxItems: &negative, Items: &Schema{Type: Types{"integer"}}},
+		{Type: Types{"object"}, Properties: map[string]*Schema{"tags": {Type: Types{"array"}, MinItems: &negative}}},
+	} {
+		if _, err = doc.Synthesize(schema); err == nil {
+			t.Errorf("Synthesize(%+v) succeeded, want negative size error", schema)
+		}
+	}
+}
+
+func TestSynthesizeCycle(t *testing.T) {
+	doc := parseSpec(t, `
+openapi: 3.0.0
+paths: {}
+components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        name:
+          type: string
+        next:
+          $ref: "#/components/schemas/Node"
+`)
+
+	got, err := doc.Synthesize(&Schema{Ref: "#/components/schemas/Node"})
+	if err != nil {
+		t.Fatalf("Synthesize error: %s", err)
+	}
+	if want := map[string]interface{}{"name": "string"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Synthesize = %#v, want %#v", got, want)
+	}
+}
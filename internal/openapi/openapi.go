@@ -0,0 +1,263 @@
+package openapi
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// OpenAPI 3 document subset needed to produce and check responses. Documents are read
+// with yaml decoder, so both YAML and JSON are accepted. Only local component refs are supported
+
+type Document struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Servers    []Server             `yaml:"servers"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+}
+
+type Server struct {
+	URL string `yaml:"url"`
+}
+
+type Components struct {
+	Schemas   map[string]*Schema   `yaml:"schemas"`
+	Responses map[string]*Response `yaml:"responses"`
+	Examples  map[string]*Example  `yaml:"examples"`
+	Headers   map[string]*Header   `yaml:"headers"`
+}
+
+type PathItem struct {
+	Get     *Operation `yaml:"get"`
+	Put     *Operation `yaml:"put"`
+	Post    *Operation `yaml:"post"`
+	Delete  *Operation `yaml:"delete"`
+	Options *Operation `yaml:"options"`
+	Head    *Operation `yaml:"head"`
+	Patch   *Operation `yaml:"patch"`
+	Trace   *Operation `yaml:"trace"`
+}
+
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+type Response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Headers     map[string]*Header    `yaml:"headers"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+type Header struct {
+	Ref      string      `yaml:"$ref"`
+	Required bool        `yaml:"required"`
+	Schema   *Schema     `yaml:"schema"`
+	Example  interface{} `yaml:"example"`
+}
+
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  interface{}         `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+type Example struct {
+	Ref   string      `yaml:"$ref"`
+	Value interface{} `yaml:"value"`
+}
+
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 Types              `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Nullable             bool               `yaml:"nullable"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Example              interface{}        `yaml:"example"`
+	Default              interface{}        `yaml:"default"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties interface{}        `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	Pattern              string             `yaml:"pattern"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+}
+
+// Types Schema type, single name in OpenAPI 3.0 and name or list of names in 3.1
+type Types []string
+
+func (t *Types) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = Types{value.Value}
+		return nil
+	}
+
+	var types []string
+	if err := value.Decode(&types); err != nil {
+		return err
+	}
+	*t = types
+
+	return nil
+}
+
+// Has Check type is allowed
+func (t Types) Has(name string) bool {
+	for _, v := range t {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse Read YAML or JSON document
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %s", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, 3.x expected", doc.OpenAPI)
+	}
+
+	return &doc, nil
+}
+
+// BasePath Path of the first server url, paths of operations are relative to it
+func (d *Document) BasePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+
+	u, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// Operations Document operations by method, methods are upper case
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := map[string]*Operation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"OPTIONS": p.Options, "HEAD": p.Head, "PATCH": p.Patch, "TRACE": p.Trace,
+	}
+	for method, op := range ops {
+		if op == nil {
+			delete(ops, method)
+		}
+	}
+
+	return ops
+}
+
+// SortedPaths Document paths in stable order
+func (d *Document) SortedPaths() []string {
+	paths := make([]string, 0, len(d.Paths))
+	for p := range d.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// refName Component name of local ref of given section
+func refName(ref string, section string) (string, error) {
+	prefix := "#/components/" + section + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported ref %s", ref)
+	}
+
+	name := strings.TrimPrefix(ref, prefix)
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(name), nil
+}
+
+// maxRefDepth Limit of refs chain to stop on ref cycles
+const maxRefDepth = 32
+
+// Schema Resolve schema ref
+func (d *Document) Schema(s *Schema) (*Schema, error) {
+	for depth := 0; s != nil && s.Ref != ""; depth++ {
+		if depth == maxRefDepth {
+			return nil, fmt.Errorf("ref %s is too deep", s.Ref)
+		}
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			return nil, err
+		}
+		if s = d.Components.Schemas[name]; s == nil {
+			return nil, fmt.Errorf("schema %s not found", name)
+		}
+	}
+
+	return s, nil
+}
+
+// Response Resolve response ref
+func (d *Document) Response(r *Response) (*Response, error) {
+	for depth := 0; r != nil && r.Ref != ""; depth++ {
+		if depth == maxRefDepth {
+			return nil, fmt.Errorf("ref %s is too deep", r.Ref)
+		}
+		name, err := refName(r.Ref, "responses")
+		if err != nil {
+			return nil, err
+		}
+		if r = d.Components.Responses[name]; r == nil {
+			return nil, fmt.Errorf("response %s not found", name)
+		}
+	}
+
+	return r, nil
+}
+
+// Header Resolve header ref
+func (d *Document) Header(h *Header) (*Header, error) {
+	for depth := 0; h != nil && h.Ref != ""; depth++ {
+		if depth == maxRefDepth {
+			return nil, fmt.Errorf("ref %s is too deep", h.Ref)
+		}
+		name, err := refName(h.Ref, "headers")
+		if err != nil {
+			return nil, err
+		}
+		if h = d.Components.Headers[name]; h == nil {
+			return nil, fmt.Errorf("header %s not found", name)
+		}
+	}
+
+	return h, nil
+}
+
+// Example Resolve example ref
+func (d *Document) Example(e *Example) (*Example, error) {
+	for depth := 0; e != nil && e.Ref != ""; depth++ {
+		if depth == maxRefDepth {
+			return nil, fmt.Errorf("ref %s is too deep", e.Ref)
+		}
+		name, err := refName(e.Ref, "examples")
+		if err != nil {
+			return nil, err
+		}
+		if e = d.Components.Examples[name]; e == nil {
+			return nil, fmt.Errorf("example %s not found", name)
+		}
+	}
+
+	return e, nil
+}
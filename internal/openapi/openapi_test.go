@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSpec = `
+openapi: 3.0.3
+servers:
+  - url: https://api.example.com/v1/
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: pets
+          headers:
+            X-Total:
+              required: true
+              schema:
+                type: integer
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Pet"
+    post:
+      responses:
+        "201":
+          $ref: "#/components/responses/Created"
+  /pets/{id}:
+    get:
+      responses:
+        "200":
+          description: pet
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+        "404":
+          description: not found
+  /pets/mine:
+    get:
+      responses:
+        2XX:
+          description: own pets
+          content:
+            text/plain:
+              example: mine
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      additionalProperties: false
+      properties:
+        id:
+          type: integer
+          minimum: 1
+        name:
+          type: string
+          minLength: 2
+        tag:
+          type: [string, "null"]
+        owner:
+          $ref: "#/components/schemas/Owner"
+    Owner:
+      type: object
+      properties:
+        pets:
+          type: array
+          items:
+            $ref: "#/components/schemas/Pet"
+  responses:
+    Created:
+      description: created
+      content:
+        application/json:
+          example: {"id": 1}
+`
+
+func parseSpec(t *testing.T, spec string) *Document {
+	t.Helper()
+	doc, err := Parse([]byte(spec))
+	if err != nil {
+		t.Fatalf("Parse error: %s", err)
+	}
+	return doc
+}
+
+func TestParse(t *testing.T) {
+	doc := parseSpec(t, testSpec)
+
+	if got := doc.BasePath(); got != "/v1" {
+		t.Errorf("BasePath = %q, want /v1", got)
+	}
+	if got := strings.Join(doc.SortedPaths(), " "); got != "/pets /pets/mine /pets/{id}" {
+		t.Errorf("SortedPaths = %q", got)
+	}
+	if ops := doc.Paths["/pets"].Operations(); len(ops) != 2 || ops["GET"] == nil || ops["POST"] == nil {
+		t.Errorf("Operations = %v, want GET and POST", ops)
+	}
+
+	tag := doc.Components.Schemas["Pet"].Properties["tag"]
+	if !tag.Type.Has("string") || !tag.Type.Has("null") {
+		t.Errorf("type list = %v, want string and null", tag.Type)
+	}
+	if s, err := doc.Schema(&Schema{Ref: "#/components/schemas/Owner"}); err != nil || s == nil || s.Properties["pets"] == nil {
+		t.Errorf("Schema ref resolved to %v, %v", s, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{"swagger 2", `{"swagger": "2.0", "paths": {}}`, "unsupported OpenAPI version"},
+		{"not a document", "- a\n- b", "invalid OpenAPI document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.spec))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		key  string
+		code int
+		ok   bool
+	}{
+		{"200", 200, true},
+		{"2XX", 200, true},
+		{"4xx", 400, true},
+		{"default", 0, false},
+		{"600", 0, false},
+	}
+
+	for _, tt := range tests {
+		code, ok := StatusCode(tt.key)
+		if code != tt.code || ok != tt.ok {
+			t.Errorf("StatusCode(%q) = %d, %v, want %d, %v", tt.key, code, ok, tt.code, tt.ok)
+		}
+	}
+}
+
+func TestFindOperation(t *testing.T) {
+	doc := parseSpec(t, testSpec)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{"GET", "/v1/pets", "/pets", true},
+		{"post", "/v1/pets", "/pets", true},
+		{"GET", "/v1/pets/7", "/pets/{id}", true},
+		{"GET", "/v1/pets/mine", "/pets/mine", true},
+		{"DELETE", "/v1/pets/7", "", false},
+		{"GET", "/v1/pets/", "", false},
+		{"GET", "/pets", "", false},
+	}
+
+	for _, tt := range tests {
+		_, specPath, ok := doc.FindOperation(tt.method, tt.path)
+		if specPath != tt.want || ok != tt.ok {
+			t.Errorf("FindOperation(%s %s) = %q, %v, want %q, %v", tt.method, tt.path, specPath, ok, tt.want, tt.ok)
+		}
+	}
+}
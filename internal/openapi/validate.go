@@ -0,0 +1,359 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FindOperation Operation for request method and path, spec path parameters match any path segment
+func (d *Document) FindOperation(method string, path string) (*Operation, string, bool) {
+	base := d.BasePath()
+	if !strings.HasPrefix(path, base) {
+		return nil, "", false
+	}
+	segments := strings.Split(strings.TrimPrefix(path, base), "/")
+
+	// Literal segments take precedence over parameters, like /pets/mine over /pets/{id}
+	bestPath := ""
+	bestLiterals := -1
+	for _, specPath := range d.SortedPaths() {
+		item := d.Paths[specPath]
+		if item == nil || item.Operations()[strings.ToUpper(method)] == nil {
+			continue
+		}
+		if literals, ok := matchSegments(strings.Split(specPath, "/"), segments); ok && literals > bestLiterals {
+			bestPath, bestLiterals = specPath, literals
+		}
+	}
+
+	if bestLiterals < 0 {
+		return nil, "", false
+	}
+
+	return d.Paths[bestPath].Operations()[strings.ToUpper(method)], bestPath, true
+}
+
+func matchSegments(spec []string, path []string) (int, bool) {
+	if len(spec) != len(path) {
+		return 0, false
+	}
+
+	literals := 0
+	for i, s := range spec {
+		switch {
+		case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			if path[i] == "" {
+				return 0, false
+			}
+		case s == path[i]:
+			literals++
+		default:
+			return 0, false
+		}
+	}
+
+	return literals, true
+}
+
+// findResponse Response documented for status: exact code, code range or default
+func (d *Document) findResponse(op *Operation, status int) (*Response, bool, error) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if r, ok := op.Responses[key]; ok {
+			r, err := d.Response(r)
+			return r, r != nil, err
+		}
+	}
+
+	return nil, false, nil
+}
+
+// findMediaType Documented media type for content type, wildcard media ranges are accepted
+func findMediaType(content map[string]*MediaType, contentType string) (string, *MediaType, bool) {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mt, ok := content[mediaType]; ok {
+		return mediaType, mt, true
+	}
+
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "*/*" || strings.HasSuffix(name, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(name, "*")) {
+			return name, content[name], true
+		}
+	}
+
+	return "", nil, false
+}
+
+// ValidateResponse Check response status, required headers and JSON body against operation contract.
+// Body is not checked if checkBody is false
+func (d *Document) ValidateResponse(method string, path string, status int, header http.Header, body []byte, checkBody bool) error {
+	op, specPath, ok := d.FindOperation(method, path)
+	if !ok {
+		return fmt.Errorf("operation %s %s not found in contract", method, path)
+	}
+
+	resp, ok, err := d.findResponse(op, status)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("status %d not documented for %s %s", status, method, specPath)
+	}
+
+	for name, h := range resp.Headers {
+		if h, err = d.Header(h); err != nil {
+			return err
+		}
+		if h != nil && h.Required && header.Get(name) == "" {
+			return fmt.Errorf("required header %s missing", name)
+		}
+	}
+
+	if !checkBody {
+		return nil
+	}
+
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d of %s %s has no body in contract", status, method, specPath)
+		}
+		return nil
+	}
+	if len(body) == 0 {
+		return nil
+	}
+
+	contentType := header.Get("Content-Type")
+	mediaType, mt, ok := findMediaType(resp.Content, contentType)
+	if !ok {
+		return fmt.Errorf("content type %q not documented for status %d of %s %s", contentType, status, method, specPath)
+	}
+	if mt == nil || mt.Schema == nil || !IsJSON(mediaType) && !IsJSON(contentType) {
+		return nil
+	}
+
+	var value interface{}
+	if err = json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("body is not valid JSON: %s", err)
+	}
+
+	return d.ValidateValue(mt.Schema, value)
+}
+
+// ValidateValue Check JSON decoded value against schema
+func (d *Document) ValidateValue(s *Schema, value interface{}) error {
+	return d.validateValue(s, value, "$", nil)
+}
+
+func (d *Document) validateValue(s *Schema, value interface{}, at string, refs []string) error {
+	if s != nil && s.Ref != "" {
+		for _, ref := range refs {
+			// Same schema for the same value means infinite recursion, values are nested otherwise
+			if ref == s.Ref+"@"+at {
+				return nil
+			}
+		}
+		refs = append(refs[:len(refs):len(refs)], s.Ref+"@"+at)
+	}
+
+	s, err := d.Schema(s)
+	if err != nil || s == nil {
+		return err
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type.Has("null") || len(s.Type) == 0 && len(s.AllOf)+len(s.OneOf)+len(s.AnyOf) == 0 {
+			return nil
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		if err := d.validateValue(sub, value, at, refs); err != nil {
+			return err
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		var firstErr error
+		for _, sub := range s.AnyOf {
+			if firstErr = d.validateValue(sub, value, at, refs); firstErr == nil {
+				break
+			}
+		}
+		if firstErr != nil {
+			return fmt.Errorf("%s: value matches none of anyOf schemas: %s", at, firstErr)
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		var firstErr error
+		for _, sub := range s.OneOf {
+			if err := d.validateValue(sub, value, at, refs); err == nil {
+				matched++
+			} else if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if matched == 0 {
+			return fmt.Errorf("%s: value matches none of oneOf schemas: %s", at, firstErr)
+		}
+		if matched > 1 {
+			return fmt.Errorf("%s: value matches %d oneOf schemas", at, matched)
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return fmt.Errorf("%s: value %v not in enum", at, value)
+	}
+
+	if len(s.Type) > 0 && !matchesType(s, value) {
+		return fmt.Errorf("%s: %s expected, got %s", at, strings.Join(s.Type, " or "), typeName(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return d.validateObject(s, v, at, refs)
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s: at least %d items expected", at, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("%s: at most %d items expected", at, *s.MaxItems)
+		}
+		for i, item := range v {
+			if err := d.validateValue(s.Items, item, fmt.Sprintf("%s[%d]", at, i), refs); err != nil {
+				return err
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fmt.Errorf("%s: at least %d characters expected", at, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fmt.Errorf("%s: at most %d characters expected", at, *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err == nil && !re.MatchString(v) {
+				return fmt.Errorf("%s: value %q does not match pattern %s", at, v, s.Pattern)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s: value %v is less than minimum %v", at, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s: value %v is greater than maximum %v", at, v, *s.Maximum)
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]interface{}, at string, refs []string) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: required property %s missing", at, name)
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties == false {
+				return fmt.Errorf("%s: property %s not allowed", at, name)
+			}
+			continue
+		}
+		if err := d.validateValue(prop, obj[name], at+"."+name, refs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func matchesType(s *Schema, value interface{}) bool {
+	for _, t := range s.Type {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" || s.Nullable {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64:
+			if t == "number" || t == "integer" && v == math.Trunc(v) {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// inEnum Compare value with yaml decoded enum values in JSON form
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		data, err := json.Marshal(normalize(e))
+		if err != nil {
+			continue
+		}
+		var v interface{}
+		if json.Unmarshal(data, &v) == nil && reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateValue(t *testing.T) {
+	doc := parseSpec(t, testSpec)
+	pet := &Schema{Ref: "#/components/schemas/Pet"}
+
+	tests := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"valid", `{"id": 1, "name": "Rex"}`, ""},
+		{"nullable tag", `{"id": 1, "name": "Rex", "tag": null}`, ""},
+		{"nested cycle", `{"id": 1, "name": "Rex", "owner": {"pets": [{"id": 2, "name": "Tom"}]}}`, ""},
+		{"required missing", `{"id": 1}`, "required property name missing"},
+		{"wrong type", `{"id": "1", "name": "Rex"}`, "$.id: integer expected, got string"},
+		{"not integer", `{"id": 1.5, "name": "Rex"}`, "integer expected"},
+		{"minimum", `{"id": 0, "name": "Rex"}`, "less than minimum"},
+		{"min length", `{"id": 1, "name": "R"}`, "at least 2 characters"},
+		{"additional property", `{"id": 1, "name": "Rex", "age": 3}`, "property age not allowed"},
+		{"nested error path", `{"id": 1, "name": "Rex", "owner": {"pets": [{"id": 2}]}}`, "$.owner.pets[0]: required property name"},
+		{"null object", `null`, "object expected, got null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatalf("bad test value: %s", err)
+			}
+
+			err := doc.ValidateValue(pet, value)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	doc := &Document{}
+	str := &Schema{Type: Types{"string"}}
+	num := &Schema{Type: Types{"number"}}
+	short := &Schema{Type: Types{"string"}, MaxLength: new(int)}
+	*short.MaxLength = 3
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		value  interface{}
+		err    string
+	}{
+		{"any of", &Schema{AnyOf: []*Schema{str, num}}, 1.0, ""},
+		{"any of none", &Schema{AnyOf: []*Schema{str, num}}, true, "none of anyOf"},
+		{"one of", &Schema{OneOf: []*Schema{short, num}}, "abcd", "none of oneOf"},
+		{"one of several", &Schema{OneOf: []*Schema{str, short}}, "ab", "matches 2 oneOf"},
+		{"all of", &Schema{AllOf: []*Schema{str, short}}, "abcd", "at most 3 characters"},
+		{"enum", &Schema{Enum: []interface{}{"a", 1}}, 1.0, ""},
+		{"not in enum", &Schema{Enum: []interface{}{"a", 1}}, "b", "not in enum"},
+		{"pattern", &Schema{Type: Types{"string"}, Pattern: "^[a-z]+$"}, "ab1", "does not match pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateValue(tt.schema, tt.value)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := parseSpec(t, testSpec)
+	jsonHeader := http.Header{"Content-Type": {"application/json"}, "X-Total": {"1"}}
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		status    int
+		header    http.Header
+		body      string
+		checkBody bool
+		err       string
+	}{
+		{"valid", "GET", "/v1/pets", 200, jsonHeader, `[{"id": 1, "name": "Rex"}]`, true, ""},
+		{"unknown operation", "PUT", "/v1/pets", 200, jsonHeader, "", true, "not found in contract"},
+		{"undocumented status", "GET", "/v1/pets", 500, jsonHeader, "", true, "status 500 not documented"},
+		{"required header", "GET", "/v1/pets", 200, http.Header{"Content-Type": {"application/json"}}, "[]", true, "required header X-Total"},
+		{"invalid body", "GET", "/v1/pets", 200, jsonHeader, `[{"id": 1}]`, true, "required property name"},
+		{"body not checked", "GET", "/v1/pets", 200, jsonHeader, `[{"id": 1}]`, false, ""},
+		{"not json", "GET", "/v1/pets", 200, jsonHeader, `[`, true, "not valid JSON"},
+		{"undocumented content type", "GET", "/v1/pets/1", 200, http.Header{"Content-Type": {"text/html"}}, "<p>", true, "content type"},
+		{"body of bodyless status", "GET", "/v1/pets/1", 404, nil, "missing", true, "has no body"},
+		{"code range", "GET", "/v1/pets/mine", 204, http.Header{"Content-Type": {"text/plain"}}, "x", true, ""},
+		{"ref response", "POST", "/v1/pets", 201, jsonHeader, `{"id": 1}`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			err := doc.ValidateResponse(tt.method, tt.path, tt.status, header, []byte(tt.body), tt.checkBody)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Postman collection v2.1 subset with saved example responses, see https://schema.postman.com/
+
+type Collection struct {
+	Info     Info       `json:"info"`
+	Item     []Item     `json:"item"`
+	Variable []Variable `json:"variable"`
+}
+
+type Info struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+type Variable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Item Request with saved responses or folder of items
+type Item struct {
+	Name     string     `json:"name"`
+	Item     []Item     `json:"item"`
+	Request  *Request   `json:"request"`
+	Response []Response `json:"response"`
+}
+
+type Header struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type Request struct {
+	Method string   `json:"method"`
+	URL    URL      `json:"url"`
+	Header []Header `json:"header"`
+}
+
+// URL Request url, given as string or object with raw string. Object without raw string is built from its parts
+type URL struct {
+	Raw      string       `json:"raw"`
+	Protocol string       `json:"protocol"`
+	Host     Segments     `json:"host"`
+	Path     Segments     `json:"path"`
+	Query    []QueryParam `json:"query"`
+}
+
+type QueryParam struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Segments Host or path parts, given as string or list of strings and {"value": ...} objects
+type Segments []string
+
+func (u *URL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+
+	type plain URL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+func (s *Segments) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = Segments{str}
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	*s = make(Segments, 0, len(items))
+	for _, item := range items {
+		var obj struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(item, &str); err == nil {
+			*s = append(*s, str)
+		} else if err = json.Unmarshal(item, &obj); err == nil {
+			*s = append(*s, obj.Value)
+		} else {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String Raw url or url built from protocol, host, path and enabled query params
+func (u URL) String() string {
+	if u.Raw != "" || len(u.Host) == 0 && len(u.Path) == 0 {
+		return u.Raw
+	}
+
+	var b strings.Builder
+	if host := strings.Join(u.Host, "."); host != "" {
+		// Variable host like {{baseUrl}} usually includes protocol
+		protocol := u.Protocol
+		if protocol == "" && !strings.HasPrefix(host, "{{") {
+			protocol = "http"
+		}
+		if protocol != "" {
+			b.WriteString(protocol + "://")
+		}
+		b.WriteString(host)
+	}
+	for _, segment := range u.Path {
+		b.WriteString("/" + strings.Trim(segment, "/"))
+	}
+
+	sep := "?"
+	for _, q := range u.Query {
+		if q.Disabled {
+			continue
+		}
+		b.WriteString(sep + q.Key)
+		if q.Value != "" {
+			b.WriteString("=" + q.Value)
+		}
+		sep = "&"
+	}
+
+	return b.String()
+}
+
+type Response struct {
+	Name            string   `json:"name"`
+	OriginalRequest *Request `json:"originalRequest"`
+	Code            int      `json:"code"`
+	Header          []Header `json:"header"`
+	Body            string   `json:"body"`
+}
+
+// Example Saved response with request it was received for
+type Example struct {
+	Name    string
+	Method  string
+	URL     string
+	Status  int
+	Headers map[string]string
+	Body    string
+}
+
+var variableRe = regexp.MustCompile(`\{\{([^}]*)\}\}`)
+
+// Read Decode collection
+func Read(r io.Reader) (*Collection, error) {
+	var c Collection
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid Postman collection: %s", err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "v2.1") {
+		return nil, fmt.Errorf("unsupported Postman collection schema %s, v2.1 expected", c.Info.Schema)
+	}
+
+	return &c, nil
+}
+
+// Examples Saved responses of all collection requests in collection order. Collection variables are
+// substituted to urls, unknown variable at url start is taken as base url and removed.
+// Requests without saved responses are counted as skipped
+func (c *Collection) Examples() ([]Example, int) {
+	vars := make(map[string]string, len(c.Variable))
+	for _, v := range c.Variable {
+		vars[v.Key] = v.Value
+	}
+
+	var examples []Example
+	skipped := 0
+	var walk func(items []Item)
+	walk = func(items []Item) {
+		for _, item := range items {
+			if item.Request == nil {
+				walk(item.Item)
+				continue
+			}
+			if len(item.Response) == 0 {
+				skipped++
+				continue
+			}
+
+			for _, resp := range item.Response {
+				req := item.Request
+				if resp.OriginalRequest != nil {
+					req = resp.OriginalRequest
+				}
+
+				method := strings.ToUpper(req.Method)
+				if method == "" {
+					method = "GET"
+				}
+
+				headers := make(map[string]string)
+				for _, h := range resp.Header {
+					if !h.Disabled {
+						headers[h.Key] = h.Value
+					}
+				}
+
+				examples = append(examples, Example{
+					Name:    resp.Name,
+					Method:  method,
+					URL:     resolveURL(req.URL.String(), vars),
+					Status:  resp.Code,
+					Headers: headers,
+					Body:    resp.Body,
+				})
+			}
+		}
+	}
+	walk(c.Item)
+
+	return examples, skipped
+}
+
+// resolveURL Substitute known variables and drop unknown base url variable
+func resolveURL(raw string, vars map[string]string) string {
+	raw = variableRe.ReplaceAllStringFunc(raw, func(v string) string {
+		if value, ok := vars[strings.TrimSpace(v[2:len(v)-2])]; ok {
+			return value
+		}
+		return v
+	})
+
+	if loc := variableRe.FindStringIndex(raw); loc != nil && loc[0] == 0 {
+		raw = raw[loc[1]:]
+	}
+
+	return raw
+}
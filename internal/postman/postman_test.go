@@ -0,0 +1,98 @@
+package postman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCollection = `{
+  "info": {"name": "shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "variable": [{"key": "version", "value": "v2"}],
+  "item": [
+    {"name": "users", "item": [
+      {"name": "list", "request": {"method": "get", "url": "{{baseUrl}}/{{version}}/users?page=1"},
+        "response": [{"name": "ok", "code": 200, "body": "[]",
+          "header": [{"key": "Content-Type", "value": "application/json"}, {"key": "X-Old", "value": "1", "disabled": true}]}]},
+      {"name": "get", "request": {"method": "GET", "url": {"raw": "https://api.example.com/users/:id"}},
+        "response": [
+          {"name": "found", "code": 200, "body": "{}"},
+          {"name": "missing", "code": 404, "originalRequest": {"method": "GET", "url": {"raw": "https://api.example.com/users/0"}}}
+        ]}
+    ]},
+    {"name": "no examples", "request": {"method": "DELETE", "url": "https://api.example.com/users/1"}},
+    {"name": "parts", "request": {"method": "POST", "url": {
+        "protocol": "https", "host": ["api", "example", "com"], "path": ["orders", {"type": "string", "value": ":id"}],
+        "query": [{"key": "dry", "value": "1"}, {"key": "off", "value": "x", "disabled": true}]}},
+      "response": [{"name": "created", "code": 201}]},
+    {"name": "variable host", "request": {"url": {"host": ["{{baseUrl}}"], "path": "ping"}},
+      "response": [{"name": "pong", "code": 204}]}
+  ]
+}`
+
+func TestExamples(t *testing.T) {
+	c, err := Read(strings.NewReader(testCollection))
+	if err != nil {
+		t.Fatalf("Read error: %s", err)
+	}
+
+	examples, skipped := c.Examples()
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+
+	want := []Example{
+		{Name: "ok", Method: "GET", URL: "/v2/users?page=1", Status: 200, Body: "[]",
+			Headers: map[string]string{"Content-Type": "application/json"}},
+		{Name: "found", Method: "GET", URL: "https://api.example.com/users/:id", Status: 200, Body: "{}", Headers: map[string]string{}},
+		{Name: "missing", Method: "GET", URL: "https://api.example.com/users/0", Status: 404, Headers: map[string]string{}},
+		{Name: "created", Method: "POST", URL: "https://api.example.com/orders/:id?dry=1", Status: 201, Headers: map[string]string{}},
+		{Name: "pong", Method: "GET", URL: "/ping", Status: 204, Headers: map[string]string{}},
+	}
+	if len(examples) != len(want) {
+		t.Fatalf("got %d examples, want %d: %+v", len(examples), len(want), examples)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(examples[i], want[i]) {
+			t.Errorf("example %d = %+v, want %+v", i, examples[i], want[i])
+		}
+	}
+}
+
+func TestURLString(t *testing.T) {
+	tests := []struct {
+		name string
+		url  URL
+		want string
+	}{
+		{"raw wins", URL{Raw: "http://a/b", Path: Segments{"c"}}, "http://a/b"},
+		{"host without protocol", URL{Host: Segments{"localhost:8080"}, Path: Segments{"a", "b"}}, "http://localhost:8080/a/b"},
+		{"path only", URL{Path: Segments{"a"}}, "/a"},
+		{"query without value", URL{Host: Segments{"h"}, Query: []QueryParam{{Key: "flag"}, {Key: "q", Value: "1"}}}, "http://h?flag&q=1"},
+		{"empty", URL{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.url.String(); got != tt.want {
+			t.Errorf("%s: String() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"not json", "{", "invalid Postman collection"},
+		{"old schema", `{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.0.0/"}}`, "unsupported Postman collection schema"},
+	}
+
+	for _, tt := range tests {
+		_, err := Read(strings.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
+	"io"
+	"io/fs"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 )
 
-func StubApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+func StubApiHandler(stubStore stubs.StubStorage, targetRegistry *targets.Registry) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		targetParam := q.Get("target")
@@ -25,7 +29,7 @@ func StubApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
 		if pathParam == "" {
 			targetHandle(w, r, stubStore)
 		} else {
-			targetStubHandle(w, r, stubStore)
+			targetStubHandle(w, r, stubStore, targetRegistry)
 		}
 	}
 
@@ -58,13 +62,47 @@ func targetHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.StubSt
 	}
 }
 
-func targetStubHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.StubStorage) {
+// decodeStubData Stub from stub API request. Legacy clients send code and timeout as strings
+// and headers as JSON encoded string, such values are converted before decode
+func decodeStubData(body io.Reader) (stubs.ServiceStub, error) {
+	var stubData stubs.ServiceStub
+
+	var raw map[string]interface{}
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return stubData, err
+	}
+
+	for _, field := range []string{"code", "timeout"} {
+		if v, ok := raw[field].(string); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil && field == "code" {
+				return stubData, fmt.Errorf("invalid code %s", v)
+			}
+			raw[field] = n
+		}
+	}
+	if v, ok := raw["headers"].(string); ok {
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(v), &headers); err != nil {
+			return stubData, fmt.Errorf("invalid headers: %s", err)
+		}
+		raw["headers"] = headers
+	}
+
+	data, _ := json.Marshal(raw)
+	err := json.Unmarshal(data, &stubData)
+
+	return stubData, err
+}
+
+func targetStubHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.StubStorage, targetRegistry *targets.Registry) {
 	q := r.URL.Query()
 	targetParam := q.Get("target")
 	pathParam := q.Get("path")
+	stubKey := stubs.NewStubKey(q.Get("method"), pathParam, q.Get("name"))
 	targetUrl, err := url.Parse(targetParam)
 
-	notFoundMessage := fmt.Sprintf("Stub %s for target %s not found", pathParam, targetUrl)
+	notFoundMessage := fmt.Sprintf("Stub %s for target %s not found", stubKey, targetUrl)
 	if err != nil {
 		http.Error(w, notFoundMessage, http.StatusNotFound)
 		return
@@ -75,7 +113,7 @@ func targetStubHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.St
 		resp := []byte("")
 		if sm, err := stubStore.GetServiceStubs(targetUrl); err == nil && sm != nil {
 			w.Header().Set("Content-Type", "application/json")
-			stub, ok := sm.Service[pathParam]
+			stub, ok := sm.Service[stubKey.String()]
 			if ok {
 				if resp, err = json.Marshal(stub); err != nil {
 					http.Error(w, fmt.Sprintf("Can`t parse stubs for target %s", targetUrl), http.StatusInternalServerError)
@@ -87,34 +125,33 @@ func targetStubHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.St
 		}
 
 	case "POST":
-		var reqData map[string]interface{}
-
-		if err = json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		stubData, err := decodeStubData(r.Body)
+		if err != nil || stubData.Code == 0 {
 			http.Error(w, "Request data not valid", http.StatusBadRequest)
 			return
 		}
 
-		code, err := strconv.Atoi(reqData["code"].(string))
-		if err != nil {
-			http.Error(w, "Request data not valid", http.StatusBadRequest)
+		if err = stubKey.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Stub path not valid: %s", err), http.StatusBadRequest)
 			return
 		}
 
-		timeout, err := strconv.Atoi(reqData["timeout"].(string))
-		if err != nil {
-			timeout = 0
+		if err = stubData.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Stub not valid: %s", err), http.StatusBadRequest)
+			return
 		}
 
-		data := reqData["data"].(string)
-
-		var headers map[string]string
-		if err = json.Unmarshal([]byte(reqData["headers"].(string)), &headers); err != nil {
-			http.Error(w, "Request data not valid", http.StatusBadRequest)
+		if err = validateTargetsContract(targetRegistry, targetUrl, stubKey, stubData); err != nil {
+			http.Error(w, fmt.Sprintf("Stub violates contract: %s", err), http.StatusUnprocessableEntity)
 			return
 		}
 
-		stubData := stubs.ServiceStub{Code: code, Data: data, Headers: headers, Timeout: timeout}
-		err = stubStore.SaveServiceStub(targetUrl, pathParam, stubData)
+		err = stubStore.SaveServiceStub(targetUrl, stubKey, stubData)
+		if err == nil {
+			// Changed stub sequence starts from the first response
+			err = stubStore.ResetSequences(targetUrl, &stubKey)
+		}
+
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		} else {
@@ -122,7 +159,7 @@ func targetStubHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.St
 		}
 
 	case "DELETE":
-		err = stubStore.RemoveServiceStub(targetUrl, pathParam)
+		err = stubStore.RemoveServiceStub(targetUrl, stubKey)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -131,3 +168,233 @@ func targetStubHandle(w http.ResponseWriter, r *http.Request, stubStore stubs.St
 		}
 	}
 }
+
+func ScenarioApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		nameParam := q.Get("name")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			states, err := stubStore.GetScenarioStates(targetUrl)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			// Scenarios without saved state are in started state
+			resp := make(map[string]string)
+			if sm, err := stubStore.GetServiceStubs(targetUrl); err == nil && sm != nil {
+				for _, name := range sm.Scenarios() {
+					resp[name] = stubs.ScenarioState(states, name)
+				}
+			}
+			for name, state := range states {
+				resp[name] = state
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			data, _ := json.Marshal(resp)
+			w.Write(data)
+
+		case "POST":
+			var reqData struct {
+				State string `json:"state"`
+			}
+			if err = json.NewDecoder(r.Body).Decode(&reqData); err != nil || nameParam == "" || reqData.State == "" {
+				http.Error(w, "Request data not valid", http.StatusBadRequest)
+				return
+			}
+
+			if err = stubStore.SetScenarioState(targetUrl, nameParam, reqData.State); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, "", http.StatusOK)
+			}
+
+		case "DELETE":
+			// Reset single scenario if name passed, all target scenarios otherwise
+			if nameParam != "" {
+				err = stubStore.SetScenarioState(targetUrl, nameParam, stubs.ScenarioStarted)
+			} else {
+				err = stubStore.ResetScenarios(targetUrl)
+			}
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, "", http.StatusOK)
+			}
+		}
+	}
+
+	return fn
+}
+
+func SequenceApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		pathParam := q.Get("path")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "DELETE":
+			// Reset single stub sequence if path passed, all target sequences otherwise
+			var stubKey *stubs.StubKey
+			if pathParam != "" {
+				key := stubs.NewStubKey(q.Get("method"), pathParam, q.Get("name"))
+				stubKey = &key
+			}
+
+			if err = stubStore.ResetSequences(targetUrl, stubKey); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, "", http.StatusOK)
+			}
+		}
+	}
+
+	return fn
+}
+
+func CoverageApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		pathParam := q.Get("path")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			// Target without stubs has empty coverage
+			sm, _ := stubStore.GetServiceStubs(targetUrl)
+			hits, err := stubStore.GetHits(targetUrl)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(stubs.NewCoverage(sm, hits))
+			w.Write(resp)
+
+		case "DELETE":
+			// Reset single stub counter if path passed, all target counters otherwise
+			var stubKey *stubs.StubKey
+			if pathParam != "" {
+				key := stubs.NewStubKey(q.Get("method"), pathParam, q.Get("name"))
+				stubKey = &key
+			}
+
+			if err = stubStore.ResetHits(targetUrl, stubKey); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, "", http.StatusOK)
+			}
+		}
+	}
+
+	return fn
+}
+
+func BodyFileApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		nameParam := q.Get("name")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" || nameParam == "" {
+			http.Error(w, "Target and file name required", http.StatusBadRequest)
+			return
+		}
+		if !fs.ValidPath(nameParam) {
+			http.Error(w, "File name must be relative path without . and .. elements", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			file, modTime, err := stubStore.GetBodyFile(targetUrl, nameParam)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Body file %s not found", nameParam), http.StatusNotFound)
+				return
+			}
+			defer file.Close()
+			http.ServeContent(w, r, nameParam, modTime, file)
+
+		case "POST", "PUT":
+			if err = stubStore.SaveBodyFile(targetUrl, nameParam, r.Body); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, "", http.StatusOK)
+			}
+		}
+	}
+
+	return fn
+}
+
+func TargetApiHandler(targetRegistry *targets.Registry) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		targetParam := r.URL.Query().Get("target")
+
+		switch r.Method {
+		case "GET":
+			var resp []byte
+			var err error
+			if targetParam == "" {
+				resp, err = json.Marshal(targetRegistry.All())
+			} else if settings, ok := targetRegistry.Get(targetParam); ok {
+				resp, err = json.Marshal(settings)
+			} else {
+				http.Error(w, fmt.Sprintf("Target %s not found", targetParam), http.StatusNotFound)
+				return
+			}
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(resp)
+
+		case "POST":
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Request data not valid", http.StatusBadRequest)
+				return
+			}
+
+			// Request data is merged to current settings, so only changed fields can be passed
+			settings, err := targetRegistry.Update(targetParam, func(s *targets.Settings) error {
+				return s.Merge(body)
+			})
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Target settings not valid: %s", err), http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("Target %s settings changed: mode %s, %d chaos rules", targetParam, settings.Mode, len(settings.Chaos))
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(settings)
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTargetModes(t *testing.T) {
+	router, _ := testRouter(t, false)
+
+	serve := func(method string, target string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/stubapi/?target=http://127.0.0.1:1&method=GET&path=/a", `{"code": 200, "data": "stub"}`); w.Code != http.StatusOK {
+		t.Fatalf("stub save status %d: %s", w.Code, w.Body)
+	}
+
+	// Upstream of /app1 is not listening, proxied requests get bad gateway
+	steps := []struct {
+		settings string
+		path     string
+		code     int
+		body     string
+	}{
+		{"", "/app1/a", http.StatusOK, "stub"},
+		{"", "/app1/b", http.StatusBadGateway, ""},
+		{`{"mode": "stub", "unmatchedCode": 501}`, "/app1/a", http.StatusOK, "stub"},
+		{"", "/app1/b", http.StatusNotImplemented, ""},
+		{`{"mode": "passthrough"}`, "/app1/a", http.StatusBadGateway, ""},
+		{`{"mode": "hybrid"}`, "/app1/a", http.StatusOK, "stub"},
+	}
+
+	for _, step := range steps {
+		if step.settings != "" {
+			if w := serve(http.MethodPost, "/targetapi/?target=/app1", step.settings); w.Code != http.StatusOK {
+				t.Fatalf("settings %s status %d: %s", step.settings, w.Code, w.Body)
+			}
+		}
+
+		w := serve(http.MethodGet, step.path, "")
+		if w.Code != step.code || step.body != "" && w.Body.String() != step.body {
+			t.Errorf("after %q: %s status %d body %q, want %d %q", step.settings, step.path, w.Code, w.Body, step.code, step.body)
+		}
+	}
+
+	if w := serve(http.MethodPost, "/targetapi/?target=/app1", `{"mode": "proxy"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown mode status %d, want 400", w.Code)
+	}
+}
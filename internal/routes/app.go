@@ -7,9 +7,13 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"path"
 	"path/filepath"
 )
 
+// inspectorPage Traffic inspector page, it is served by own handler behind auth
+const inspectorPage = "/static/inspector.html"
+
 type IndexesFileSystem struct {
 	fs http.FileSystem
 }
@@ -43,6 +47,12 @@ func (nfs IndexesFileSystem) Open(path string) (http.File, error) {
 
 func StaticHandler() http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
+		// Variants of inspector page path lead to its handler, file server would serve it without auth
+		if path.Clean(r.URL.Path) == inspectorPage {
+			http.Redirect(w, r, inspectorPage, http.StatusMovedPermanently)
+			return
+		}
+
 		h := http.FileServer(IndexesFileSystem{http.Dir("web/")})
 		h.ServeHTTP(w, r)
 	}
@@ -50,6 +60,14 @@ func StaticHandler() http.HandlerFunc {
 	return fn
 }
 
+func InspectorHandler() http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./web"+inspectorPage)
+	}
+
+	return fn
+}
+
 func RootHandler(cfg *config.StubRouterConfig, sessionManager *scs.SessionManager) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		tmpl, err := template.ParseFiles("./web/templates/index.html")
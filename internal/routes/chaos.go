@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"github.com/overdone/stubrouter/internal/latency"
+	"github.com/overdone/stubrouter/internal/targets"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+)
+
+// dropConnection Reset client connection without response
+func dropConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		log.Panic("Connection can`t be hijacked")
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		log.Panic(err)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// serveProxy Proxy request to target with target bandwidth limit. Every chaos rule for request path is triggered
+// with its probability: latencies of triggered rules are added before proxying,
+// first triggered drop or status responds instead of proxying, so request does not reach upstream
+func serveProxy(w http.ResponseWriter, r *http.Request, proxy *httputil.ReverseProxy, settings targets.Settings, targetPath string) {
+	var delay []*latency.Profile
+	var fault *targets.ChaosRule
+
+	rules := settings.Chaos
+	for i, rule := range rules {
+		if !rule.Applies(targetPath) || rand.Float64() >= rule.Probability {
+			continue
+		}
+
+		if rule.Latency != nil {
+			delay = append(delay, rule.Latency)
+		}
+		if fault == nil && (rule.Drop || rule.Status != 0) {
+			fault = &rules[i]
+		}
+	}
+
+	for _, p := range delay {
+		if err := latency.Sleep(r.Context(), p.Sample()); err != nil {
+			return
+		}
+	}
+
+	switch {
+	case fault == nil:
+		proxy.ServeHTTP(throttle(w, r, settings.Bandwidth), r)
+	case fault.Drop:
+		log.Printf("Chaos: drop %s connection", targetPath)
+		dropConnection(w)
+	default:
+		log.Printf("Chaos: respond %s with %d", targetPath, fault.Status)
+		body := fault.Body
+		if body == "" {
+			body = http.StatusText(fault.Status)
+		}
+		http.Error(w, body, fault.Status)
+	}
+}
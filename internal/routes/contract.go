@@ -0,0 +1,206 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/openapi"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxContractBody Upstream responses with larger body are not checked, body is buffered for check
+const maxContractBody = 1 << 20
+
+// ContractViolationHeader Response header flagging upstream response not conforming to target contract
+const ContractViolationHeader = "X-Stubrouter-Contract-Violation"
+
+var pathVarRe = regexp.MustCompile(`\{\w+\}`)
+
+// contractMethods Methods stub for any method is checked with
+var contractMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodTrace,
+}
+
+// validateStubContract Check stub responses against contract. Stubs with path wildcards and regexps,
+// faults and streams are not checked, bodies of templates and binary stubs are not checked.
+// Path variable placeholders are checked with sample value
+func validateStubContract(doc *openapi.Document, key stubs.StubKey, stub stubs.ServiceStub) error {
+	kind := stubs.GetPathKind(key.Path)
+	if kind == stubs.PathWildcard || kind == stubs.PathRegex || stub.Fault != "" || stub.SSE != nil || stub.WebSocket != nil {
+		return nil
+	}
+
+	// Stub for any method answers every operation documented for its path
+	methods := []string{key.Method}
+	if key.Method == stubs.AnyMethod {
+		methods = nil
+		for _, method := range contractMethods {
+			if _, _, ok := doc.FindOperation(method, key.Path); ok {
+				methods = append(methods, method)
+			}
+		}
+		if len(methods) == 0 {
+			return fmt.Errorf("no operation for %s found in contract", key.Path)
+		}
+	}
+
+	for _, method := range methods {
+		if err := validateStubResponses(doc, method, key.Path, kind, stub); err != nil {
+			if key.Method == stubs.AnyMethod {
+				return fmt.Errorf("%s: %s", method, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateStubResponses Check stub response and its sequence responses against method operation
+func validateStubResponses(doc *openapi.Document, method string, path string, kind stubs.PathKind, stub stubs.ServiceStub) error {
+	responses := []stubs.ServiceStub{stub}
+	for i := range stub.Responses {
+		resp, _ := stub.SequenceResponse(int64(i))
+		responses = append(responses, resp)
+	}
+
+	for i, resp := range responses {
+		header := make(http.Header)
+		for k, v := range resp.Headers {
+			header.Set(k, v)
+		}
+
+		data := resp.Data
+		if kind == stubs.PathTemplate {
+			data = pathVarRe.ReplaceAllString(data, "1")
+		}
+
+		checkBody := !resp.Template && !resp.IsBinary()
+		if err := doc.ValidateResponse(method, path, resp.Code, header, []byte(data), checkBody); err != nil {
+			if i > 0 {
+				return fmt.Errorf("sequence response %d: %s", i-1, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateTargetsContract Check stub against contracts of all targets proxied to stub host
+func validateTargetsContract(targetRegistry *targets.Registry, host *url.URL, key stubs.StubKey, stub stubs.ServiceStub) error {
+	for path, settings := range targetRegistry.ForHost(host) {
+		if settings.Contract == nil {
+			continue
+		}
+		if err := validateStubContract(settings.Contract, key, stub); err != nil {
+			return fmt.Errorf("target %s contract: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+// checkUpstreamContract Log upstream responses violating contract and flag them with violation header.
+// Streamed and large bodies are not checked
+func checkUpstreamContract(doc *openapi.Document, method string, targetPath string) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		if resp.StatusCode == http.StatusSwitchingProtocols || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			return nil
+		}
+
+		var body []byte
+		checkBody := resp.Header.Get("Content-Encoding") == ""
+		if checkBody {
+			data, err := io.ReadAll(io.LimitReader(resp.Body, maxContractBody+1))
+			if err != nil {
+				return err
+			}
+			// Pass read part back to response body
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+
+			body = data
+			checkBody = len(data) <= maxContractBody
+		}
+
+		if err := doc.ValidateResponse(method, targetPath, resp.StatusCode, resp.Header, body, checkBody); err != nil {
+			log.Printf("Contract violation in %s %s upstream response: %s", method, targetPath, err)
+			resp.Header.Set(ContractViolationHeader, strings.ReplaceAll(err.Error(), "\n", " "))
+		}
+
+		return nil
+	}
+}
+
+// chainResponse Run response modifiers in order, first error stops chain
+func chainResponse(modifiers ...func(*http.Response) error) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		for _, modify := range modifiers {
+			if modify == nil {
+				continue
+			}
+			if err := modify(resp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func ContractApiHandler(targetRegistry *targets.Registry) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		targetParam := r.URL.Query().Get("target")
+
+		switch r.Method {
+		case "POST", "PUT":
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Request data not valid", http.StatusBadRequest)
+				return
+			}
+			doc, err := openapi.Parse(data)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			_, err = targetRegistry.Update(targetParam, func(s *targets.Settings) error {
+				s.Contract = doc
+				return nil
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			log.Printf("OpenAPI contract attached to target %s", targetParam)
+			http.Error(w, "", http.StatusOK)
+
+		case "DELETE":
+			_, err := targetRegistry.Update(targetParam, func(s *targets.Settings) error {
+				s.Contract = nil
+				s.ValidateUpstream = false
+				return nil
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			log.Printf("OpenAPI contract detached from target %s", targetParam)
+			http.Error(w, "", http.StatusOK)
+		}
+	}
+
+	return fn
+}
@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"github.com/overdone/stubrouter/internal/openapi"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"strings"
+	"testing"
+)
+
+const contractSpec = `
+openapi: 3.0.0
+paths:
+  /items:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                type: array
+    post:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                type: object
+  /items/{id}:
+    delete:
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestValidateStubContract(t *testing.T) {
+	doc, err := openapi.Parse([]byte(contractSpec))
+	if err != nil {
+		t.Fatalf("Parse error: %s", err)
+	}
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+
+	tests := []struct {
+		name string
+		key  stubs.StubKey
+		stub stubs.ServiceStub
+		err  string
+	}{
+		{"documented method", stubs.NewStubKey("GET", "/items", ""), stubs.ServiceStub{Code: 200, Data: "[]", Headers: jsonHeaders}, ""},
+		{"wrong body", stubs.NewStubKey("POST", "/items", ""), stubs.ServiceStub{Code: 200, Data: "[]", Headers: jsonHeaders}, "object expected"},
+		{"undocumented method", stubs.NewStubKey("PUT", "/items", ""), stubs.ServiceStub{Code: 200}, "not found in contract"},
+		{"any method checked with every operation", stubs.NewStubKey("", "/items", ""), stubs.ServiceStub{Code: 200, Data: "[]", Headers: jsonHeaders}, "POST: $: object expected"},
+		{"any method of single operation", stubs.NewStubKey("", "/items/{id}", ""), stubs.ServiceStub{Code: 204}, ""},
+		{"any method undocumented path", stubs.NewStubKey("", "/other", ""), stubs.ServiceStub{Code: 200}, "no operation for /other"},
+		{"sequence response", stubs.NewStubKey("DELETE", "/items/{id}", ""),
+			stubs.ServiceStub{Code: 204, Responses: []stubs.StubResponse{{Code: 500}}}, "sequence response 0: status 500"},
+		{"wildcard not checked", stubs.NewStubKey("PUT", "/items/*", ""), stubs.ServiceStub{Code: 500}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStubContract(doc, tt.key, tt.stub)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// garbageSize Number of random bytes sent by garbage fault
+const garbageSize = 1024
+
+// defaultDripDelay Delay between body bytes of slow drip fault if stub drip delay not set, ms
+const defaultDripDelay = 100
+
+// writeRawHead Write status line and headers to hijacked connection
+func writeRawHead(bw *bufio.Writer, code int, headers map[string]string, contentLength int) {
+	fmt.Fprintf(bw, "HTTP/1.1 %d %s\r\n", code, http.StatusText(code))
+
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%s: %s\r\n", http.CanonicalHeaderKey(k), headers[k])
+	}
+
+	fmt.Fprintf(bw, "Content-Length: %d\r\n", contentLength)
+	fmt.Fprint(bw, "Connection: close\r\n\r\n")
+}
+
+// writeFault Break stub response by writing directly to hijacked client connection
+func writeFault(w http.ResponseWriter, r *http.Request, stub stubs.ServiceStub, data string, headers map[string]string) error {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return fmt.Errorf("connection can`t be hijacked")
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	bw := rw.Writer
+	switch stub.Fault {
+	case stubs.FaultConnectionReset:
+		// Zero linger makes close send RST instead of FIN
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetLinger(0)
+		}
+
+	case stubs.FaultEmptyResponse:
+		// Close connection without any response
+
+	case stubs.FaultTruncatedBody:
+		writeRawHead(bw, stub.Code, headers, len(data))
+		bw.WriteString(data[:len(data)/2])
+		return bw.Flush()
+
+	case stubs.FaultGarbage:
+		garbage := make([]byte, garbageSize)
+		_, _ = rand.Read(garbage)
+		bw.Write(garbage)
+		return bw.Flush()
+
+	case stubs.FaultSlowDrip:
+		delay := time.Duration(stub.DripDelay) * time.Millisecond
+		if stub.DripDelay == 0 {
+			delay = defaultDripDelay * time.Millisecond
+		}
+		writeRawHead(bw, stub.Code, headers, len(data))
+		if err = bw.Flush(); err != nil {
+			return err
+		}
+		for i := 0; i < len(data); i++ {
+			select {
+			case <-r.Context().Done():
+				return r.Context().Err()
+			case <-time.After(delay):
+			}
+			if _, err = conn.Write([]byte{data[i]}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"github.com/overdone/stubrouter/internal/stubs"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// faultServer Server responding to every request with stub fault
+func faultServer(t *testing.T, stub stubs.ServiceStub) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := writeFault(w, r, stub, stub.Data, stub.Headers); err != nil {
+			t.Errorf("writeFault error: %s", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWriteFault(t *testing.T) {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	tests := []struct {
+		fault string
+		// Client gets no response at all
+		noResponse bool
+		body       string
+		bodyErr    bool
+	}{
+		{stubs.FaultConnectionReset, true, "", false},
+		{stubs.FaultEmptyResponse, true, "", false},
+		{stubs.FaultGarbage, true, "", false},
+		{stubs.FaultTruncatedBody, false, "0123", true},
+		{stubs.FaultSlowDrip, false, "01234567", false},
+	}
+
+	for _, tt := range tests {
+		stub := stubs.ServiceStub{Code: http.StatusOK, Data: "01234567", Headers: map[string]string{"X-Fault": tt.fault},
+			Fault: tt.fault, DripDelay: 5}
+		srv := faultServer(t, stub)
+
+		start := time.Now()
+		resp, err := client.Get(srv.URL)
+		if tt.noResponse {
+			if err == nil {
+				resp.Body.Close()
+				t.Errorf("%s: got response %d, want request error", tt.fault, resp.StatusCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: request error %s", tt.fault, err)
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tt.body || (err != nil) != tt.bodyErr {
+			t.Errorf("%s: body %q, error %v, want %q, error %t", tt.fault, body, err, tt.body, tt.bodyErr)
+		}
+		if resp.ContentLength != int64(len(stub.Data)) || resp.Header.Get("X-Fault") != tt.fault {
+			t.Errorf("%s: content length %d, headers %v", tt.fault, resp.ContentLength, resp.Header)
+		}
+		if tt.fault == stubs.FaultSlowDrip && time.Since(start) < 8*5*time.Millisecond {
+			t.Errorf("%s: body sent in %s, want drip delay before every byte", tt.fault, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/har"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// skipHarHeaders Archived response headers not stored in imported stubs. Archive content is already decoded
+var skipHarHeaders = map[string]bool{
+	"Content-Encoding": true,
+}
+
+// harEntryStub Stub for archive entry with path relative to prefix. Entries outside prefix and
+// entries without response are not imported
+func harEntryStub(entry har.Entry, prefix string) (stubs.StubKey, stubs.ServiceStub, bool) {
+	reqUrl, err := url.Parse(entry.Request.URL)
+	if err != nil || entry.Response.Status == 0 {
+		return stubs.StubKey{}, stubs.ServiceStub{}, false
+	}
+
+	key, match, ok := importedStubKey(entry.Request.Method, reqUrl, prefix)
+	if !ok {
+		return stubs.StubKey{}, stubs.ServiceStub{}, false
+	}
+
+	headers := make(map[string]string)
+	for _, h := range entry.Response.Headers {
+		name := http.CanonicalHeaderKey(h.Name)
+		// HTTP/2 pseudo headers
+		if strings.HasPrefix(name, ":") || skipRecordHeaders[name] || skipHarHeaders[name] {
+			continue
+		}
+		if v, ok := headers[name]; ok {
+			headers[name] = v + ", " + h.Value
+		} else {
+			headers[name] = h.Value
+		}
+	}
+
+	stub := stubs.ServiceStub{Code: entry.Response.Status, Data: entry.Response.Content.Text, Headers: headers, Match: match}
+	if entry.Response.Content.Encoding == stubs.EncodingBase64 {
+		// Keep text content readable
+		body, err := base64.StdEncoding.DecodeString(stub.Data)
+		if err != nil || !utf8.Valid(body) {
+			stub.Encoding = stubs.EncodingBase64
+		} else {
+			stub.Data = string(body)
+		}
+	}
+
+	return key, stub, true
+}
+
+// importHar Save stubs for all archive entries, later entries for the same request replace earlier ones
+func importHar(stubStore stubs.StubStorage, targetUrl *url.URL, archive *har.HAR, prefix string) (int, int, error) {
+	imported, skipped := 0, 0
+	for _, entry := range archive.Log.Entries {
+		key, stub, ok := harEntryStub(entry, prefix)
+		if !ok {
+			skipped++
+			continue
+		}
+
+		saved, err := saveImportedStub(stubStore, targetUrl, key, stub)
+		if err != nil {
+			return imported, skipped, err
+		}
+		if saved {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	return imported, skipped, nil
+}
+
+// harSampleVar Value of path template variables in exported urls
+const harSampleVar = "1"
+
+// exportHar Archive with entry per target stub. Stubs without method are exported as GET requests,
+// query conditions of stub as request query, template variables get sample value. Wildcard and regex
+// paths are not urls, such stubs are skipped and listed in archive comment
+func exportHar(stubStore stubs.StubStorage, targetUrl *url.URL) (*har.HAR, []string, error) {
+	archive := har.New()
+
+	sm, err := stubStore.GetServiceStubs(targetUrl)
+	if err != nil || sm == nil {
+		return archive, nil, err
+	}
+
+	keys := make([]string, 0, len(sm.Service))
+	for k := range sm.Service {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var skipped []string
+	for _, k := range keys {
+		stub := sm.Service[k]
+		key := stubs.ParseStubKey(k)
+
+		kind := stubs.GetPathKind(key.Path)
+		if kind == stubs.PathWildcard || kind == stubs.PathRegex {
+			skipped = append(skipped, k)
+			continue
+		}
+
+		method := key.Method
+		if method == stubs.AnyMethod {
+			method = http.MethodGet
+		}
+
+		query := url.Values{}
+		if stub.Match != nil {
+			for name, m := range stub.Match.Query {
+				if m.Equals != "" {
+					query.Set(name, m.Equals)
+				}
+			}
+		}
+		reqUrl := strings.TrimSuffix(targetUrl.String(), "/") + stubs.FillPathVars(key.Path, harSampleVar)
+		if len(query) > 0 {
+			reqUrl += "?" + query.Encode()
+		}
+
+		entry := har.NewEntry(method, reqUrl, stub.Code)
+		entry.Response.StatusText = http.StatusText(stub.Code)
+		for name, values := range query {
+			entry.Request.QueryString = append(entry.Request.QueryString, har.NameValue{Name: name, Value: values[0]})
+		}
+
+		names := make([]string, 0, len(stub.Headers))
+		for name := range stub.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entry.Response.Headers = append(entry.Response.Headers, har.NameValue{Name: name, Value: stub.Headers[name]})
+		}
+
+		body, _, err := stubs.OpenBody(stubStore, targetUrl, stub, stub.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("stub %s body: %s", k, err)
+		}
+		content, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("stub %s body: %s", k, err)
+		}
+
+		entry.Response.Content = har.Content{Size: len(content), MimeType: stub.Headers["Content-Type"], Text: string(content)}
+		if !utf8.Valid(content) {
+			entry.Response.Content.Text = base64.StdEncoding.EncodeToString(content)
+			entry.Response.Content.Encoding = stubs.EncodingBase64
+		}
+
+		archive.Log.Entries = append(archive.Log.Entries, entry)
+	}
+
+	if len(skipped) > 0 {
+		archive.Log.Comment = "Stubs with wildcard or regex path skipped: " + strings.Join(skipped, ", ")
+	}
+
+	return archive, skipped, nil
+}
+
+func HarApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			archive, skipped, err := exportHar(stubStore, targetUrl)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if len(skipped) > 0 {
+				log.Printf("Exported %d stubs for %s to HAR, %d stubs skipped", len(archive.Log.Entries), targetUrl, len(skipped))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", targetUrl.Host+".har"))
+			resp, _ := json.Marshal(archive)
+			w.Write(resp)
+
+		case "POST":
+			archive, err := har.Read(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Prefix is target path in archived urls when archive is captured through stubrouter
+			prefix := strings.TrimSuffix(q.Get("prefix"), "/")
+			imported, skipped, err := importHar(stubStore, targetUrl, archive, prefix)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			log.Printf("Imported %d stubs for %s from HAR, %d entries skipped", imported, targetUrl, skipped)
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(map[string]int{"imported": imported, "skipped": skipped})
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/overdone/stubrouter/internal/har"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHarRoundTrip(t *testing.T) {
+	store, target := testStore(t)
+	saved := map[string]stubs.ServiceStub{
+		"GET /users":               {Code: 200, Data: `[]`, Headers: map[string]string{"Content-Type": "application/json"}},
+		"POST /orders":             {Code: 201, Data: `{"id": 1}`},
+		"GET /users/{id}":          {Code: 200, Data: `{"id": 1}`},
+		"/status":                  {Code: 204},
+		"GET /logo.png":            {Code: 200, Data: "/wA=", Encoding: stubs.EncodingBase64},
+		"GET /files/**":            {Code: 200, Data: "file"},
+		"GET regex:^/v[12]/items$": {Code: 200, Data: "items"},
+		"GET /search#q=a": {Code: 200, Data: "found", Match: &stubs.RequestMatch{
+			Query: map[string]stubs.ValueMatcher{"q": {Equals: "a"}},
+		}},
+	}
+	for k, stub := range saved {
+		if err := store.SaveServiceStub(target, stubs.ParseStubKey(k), stub); err != nil {
+			t.Fatalf("SaveServiceStub error: %s", err)
+		}
+	}
+
+	archive, skipped, err := exportHar(store, target)
+	if err != nil {
+		t.Fatalf("exportHar error: %s", err)
+	}
+	if want := []string{"GET /files/**", "GET regex:^/v[12]/items$"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+	if !strings.Contains(archive.Log.Comment, "GET /files/**") {
+		t.Errorf("archive comment %q, want skipped stubs", archive.Log.Comment)
+	}
+	for _, e := range archive.Log.Entries {
+		if strings.ContainsAny(e.Request.URL, "{}*^$") {
+			t.Errorf("exported url %s is not valid", e.Request.URL)
+		}
+	}
+
+	data, _ := json.Marshal(archive)
+	read, err := har.Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read error: %s", err)
+	}
+
+	importStore, _ := testStore(t)
+	imported, importSkipped, err := importHar(importStore, target, read, "")
+	if err != nil {
+		t.Fatalf("importHar error: %s", err)
+	}
+	if imported != 6 || importSkipped != 0 {
+		t.Errorf("imported %d, skipped %d, want 6 and 0", imported, importSkipped)
+	}
+
+	got := storedStubs(t, importStore, target)
+	want := []string{"GET /logo.png", "GET /search#q=a", "GET /status", "GET /users", "GET /users/1", "POST /orders"}
+	if !reflect.DeepEqual(keys(got), want) {
+		t.Fatalf("imported stubs %v, want %v", keys(got), want)
+	}
+
+	tests := []struct {
+		exported string
+		imported string
+	}{
+		{"GET /users", "GET /users"},
+		{"POST /orders", "POST /orders"},
+		{"GET /users/{id}", "GET /users/1"},
+		{"/status", "GET /status"},
+		{"GET /logo.png", "GET /logo.png"},
+		{"GET /search#q=a", "GET /search#q=a"},
+	}
+	for _, tt := range tests {
+		src, dst := saved[tt.exported], got[tt.imported]
+		if dst.Code != src.Code || dst.Data != src.Data || dst.Encoding != src.Encoding {
+			t.Errorf("%s: imported %d %q %q, want %d %q %q", tt.exported, dst.Code, dst.Data, dst.Encoding, src.Code, src.Data, src.Encoding)
+		}
+		if len(src.Headers) > 0 && !reflect.DeepEqual(dst.Headers, src.Headers) {
+			t.Errorf("%s: imported headers %v, want %v", tt.exported, dst.Headers, src.Headers)
+		}
+		if !reflect.DeepEqual(dst.Match, src.Match) {
+			t.Errorf("%s: imported match %+v, want %+v", tt.exported, dst.Match, src.Match)
+		}
+	}
+}
@@ -11,6 +11,7 @@ type UserSessionData struct {
 }
 
 func getSessionDataForRequest(r *http.Request, sessionManager *scs.SessionManager) *UserSessionData {
+	markSessionUsed(r)
 	val := sessionManager.Get(r.Context(), "userData")
 	data, ok := val.(*UserSessionData)
 
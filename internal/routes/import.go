@@ -0,0 +1,266 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/curl"
+	"github.com/overdone/stubrouter/internal/openapi"
+	"github.com/overdone/stubrouter/internal/postman"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// importedStubKey Stub key for imported request with path relative to prefix, requests with query become
+// query variants like recorded ones. Requests outside prefix are not imported
+func importedStubKey(method string, reqUrl *url.URL, prefix string) (stubs.StubKey, *stubs.RequestMatch, bool) {
+	path := reqUrl.Path
+	if prefix != "" {
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			return stubs.StubKey{}, nil, false
+		}
+		path = strings.TrimPrefix(path, prefix)
+	}
+
+	reqData := &stubs.RequestData{
+		Method: method,
+		Path:   "/" + strings.TrimPrefix(path, "/"),
+		Query:  reqUrl.Query(),
+	}
+	key, match := recordedStubKey(reqData)
+
+	return key, match, true
+}
+
+// saveImportedStub Save imported stub and restart its sequence. Invalid stubs are not saved
+func saveImportedStub(stubStore stubs.StubStorage, targetUrl *url.URL, key stubs.StubKey, stub stubs.ServiceStub) (bool, error) {
+	if err := key.Validate(); err != nil {
+		log.Printf("Skip imported stub %s: %s", key, err)
+		return false, nil
+	}
+	if err := stub.Validate(); err != nil {
+		log.Printf("Skip imported stub %s: %s", key, err)
+		return false, nil
+	}
+
+	if err := stubStore.SaveServiceStub(targetUrl, key, stub); err != nil {
+		return false, err
+	}
+
+	return true, stubStore.ResetSequences(targetUrl, &key)
+}
+
+// postmanPath Postman path variables like :id become path template variables
+func postmanPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if len(s) > 1 && s[0] == ':' {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// importPostman Save stubs for saved responses of collection. First response saved for request wins
+func importPostman(stubStore stubs.StubStorage, targetUrl *url.URL, collection *postman.Collection, prefix string) (int, int, error) {
+	examples, skipped := collection.Examples()
+	imported := 0
+	seen := make(map[stubs.StubKey]bool)
+
+	for _, e := range examples {
+		reqUrl, err := url.Parse(e.URL)
+		if err != nil || e.Status == 0 {
+			skipped++
+			continue
+		}
+		reqUrl.Path = postmanPath(reqUrl.Path)
+
+		key, match, ok := importedStubKey(e.Method, reqUrl, prefix)
+		if !ok || seen[key] {
+			skipped++
+			continue
+		}
+		seen[key] = true
+
+		stub := stubs.ServiceStub{Code: e.Status, Data: e.Body, Headers: e.Headers, Match: match}
+		for name := range stub.Headers {
+			if skipRecordHeaders[http.CanonicalHeaderKey(name)] || strings.EqualFold(name, "Content-Encoding") {
+				delete(stub.Headers, name)
+			}
+		}
+
+		saved, err := saveImportedStub(stubStore, targetUrl, key, stub)
+		if err != nil {
+			return imported, skipped, err
+		}
+		if saved {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	return imported, skipped, nil
+}
+
+// importCurl Save stub with default response for every curl command, requests are not sent anywhere.
+// Command url host is ignored, path is taken relative to prefix, command data becomes body condition.
+// First command for same method, path and query wins
+func importCurl(stubStore stubs.StubStorage, targetUrl *url.URL, commands []curl.Command, prefix string, response stubs.ServiceStub) (int, int, error) {
+	imported, skipped := 0, 0
+	seen := make(map[stubs.StubKey]bool)
+
+	for _, cmd := range commands {
+		cmdUrl, err := url.Parse(cmd.URL)
+		if err != nil {
+			skipped++
+			continue
+		}
+		key, match, ok := importedStubKey(cmd.Method, cmdUrl, prefix)
+		if !ok || seen[key] {
+			skipped++
+			continue
+		}
+		seen[key] = true
+
+		if bm := curlBodyMatcher(cmd); bm != nil {
+			if match == nil {
+				match = &stubs.RequestMatch{}
+			}
+			match.Body = []stubs.BodyMatcher{*bm}
+		}
+
+		stub := response
+		stub.Match = match
+		saved, err := saveImportedStub(stubStore, targetUrl, key, stub)
+		if err != nil {
+			return imported, skipped, err
+		}
+		if saved {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	return imported, skipped, nil
+}
+
+// curlBodyMatcher Condition on command data: equal JSON, equal form fields or exact body
+func curlBodyMatcher(cmd curl.Command) *stubs.BodyMatcher {
+	if cmd.Body == "" {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(cmd.Header.Get("Content-Type"))
+	switch {
+	case openapi.IsJSON(mediaType):
+		var value interface{}
+		if err := json.Unmarshal([]byte(cmd.Body), &value); err == nil {
+			return &stubs.BodyMatcher{EqualToJson: value}
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		if form, err := url.ParseQuery(cmd.Body); err == nil {
+			bm := &stubs.BodyMatcher{Form: make(map[string]stubs.ValueMatcher, len(form))}
+			for name := range form {
+				bm.Form[name] = stubs.ValueMatcher{Equals: form.Get(name)}
+			}
+			return bm
+		}
+	}
+
+	return &stubs.BodyMatcher{Regex: "^" + regexp.QuoteMeta(cmd.Body) + "$"}
+}
+
+func PostmanApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "POST":
+			collection, err := postman.Read(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			prefix := strings.TrimSuffix(q.Get("prefix"), "/")
+			imported, skipped, err := importPostman(stubStore, targetUrl, collection, prefix)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			log.Printf("Imported %d stubs for %s from Postman collection, %d requests skipped", imported, targetUrl, skipped)
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(map[string]int{"imported": imported, "skipped": skipped})
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
+
+func CurlApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "POST":
+			text, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Request data not valid", http.StatusBadRequest)
+				return
+			}
+			commands, err := curl.Parse(string(text))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid curl commands: %s", err), http.StatusBadRequest)
+				return
+			}
+
+			// Commands carry no responses, stubs would replace upstream ones with made up response
+			code, err := strconv.Atoi(q.Get("code"))
+			if err != nil || code < 100 || code > 599 {
+				http.Error(w, "Response code of imported stubs required", http.StatusBadRequest)
+				return
+			}
+			response := stubs.ServiceStub{Code: code, Data: q.Get("data")}
+			if contentType := q.Get("contentType"); contentType != "" {
+				response.Headers = map[string]string{"Content-Type": contentType}
+			}
+
+			prefix := strings.TrimSuffix(q.Get("prefix"), "/")
+			imported, skipped, err := importCurl(stubStore, targetUrl, commands, prefix, response)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			log.Printf("Imported %d stubs for %s from curl commands, %d commands skipped", imported, targetUrl, skipped)
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(map[string]int{"imported": imported, "skipped": skipped})
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"github.com/overdone/stubrouter/internal/curl"
+	"github.com/overdone/stubrouter/internal/postman"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testStore File stub storage in test temp dir
+func testStore(t *testing.T) (stubs.StubStorage, *url.URL) {
+	t.Helper()
+	target, _ := url.Parse("http://upstream.test:8080")
+	return &stubs.FileStubStorage{FsPath: t.TempDir()}, target
+}
+
+// storedStubs Stubs saved for target by key
+func storedStubs(t *testing.T, store stubs.StubStorage, target *url.URL) map[string]stubs.ServiceStub {
+	t.Helper()
+	sm, err := store.GetServiceStubs(target)
+	if err != nil {
+		t.Fatalf("GetServiceStubs error: %s", err)
+	}
+	return sm.Service
+}
+
+func keys(m map[string]stubs.ServiceStub) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPostmanPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/:id", "/users/{id}"},
+		{"/users/:id/orders/:orderId", "/users/{id}/orders/{orderId}"},
+		{"/users/:", "/users/:"},
+		{"/time/10:30", "/time/10:30"},
+		{"/plain", "/plain"},
+	}
+
+	for _, tt := range tests {
+		if got := postmanPath(tt.path); got != tt.want {
+			t.Errorf("postmanPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestImportPostman(t *testing.T) {
+	collection, err := postman.Read(strings.NewReader(`{
+  "item": [
+    {"request": {"method": "GET", "url": "{{baseUrl}}/app1/users/:id"}, "response": [
+      {"code": 200, "body": "{\"id\": 1}", "header": [{"key": "Content-Type", "value": "application/json"}, {"key": "Content-Length", "value": "9"}]},
+      {"code": 404, "body": "second example of same request"}
+    ]},
+    {"request": {"method": "GET", "url": {"host": ["{{baseUrl}}"], "path": ["app1", "users"], "query": [{"key": "page", "value": "2"}]}},
+      "response": [{"code": 200, "body": "[]"}]},
+    {"request": {"method": "GET", "url": "{{baseUrl}}/other/users"}, "response": [{"code": 200}]},
+    {"request": {"method": "GET", "url": "{{baseUrl}}/app1/status"}, "response": [{"name": "no status"}]},
+    {"request": {"method": "GET", "url": "{{baseUrl}}/app1/none"}}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("Read error: %s", err)
+	}
+
+	store, target := testStore(t)
+	imported, skipped, err := importPostman(store, target, collection, "/app1")
+	if err != nil {
+		t.Fatalf("importPostman error: %s", err)
+	}
+	// Second example, request outside prefix, example without status and request without examples
+	if imported != 2 || skipped != 4 {
+		t.Errorf("imported %d, skipped %d, want 2 and 4", imported, skipped)
+	}
+
+	saved := storedStubs(t, store, target)
+	if want := []string{"GET /users#page=2", "GET /users/{id}"}; !reflect.DeepEqual(keys(saved), want) {
+		t.Fatalf("saved stubs %v, want %v", keys(saved), want)
+	}
+
+	user := saved["GET /users/{id}"]
+	if user.Code != 200 || user.Data != `{"id": 1}` || !reflect.DeepEqual(user.Headers, map[string]string{"Content-Type": "application/json"}) {
+		t.Errorf("user stub = %+v", user)
+	}
+	page := saved["GET /users#page=2"]
+	if page.Match == nil || page.Match.Query["page"].Equals != "2" {
+		t.Errorf("query variant match = %+v", page.Match)
+	}
+}
+
+func TestImportCurl(t *testing.T) {
+	commands, err := curl.Parse(`curl 'https://prod.example.com/app1/orders?id=7&full=1' --json '{"qty": 2, "sku": "a"}'
+curl -X PUT https://prod.example.com/app1/orders -d 'qty=2&sku=a b'
+curl -X PUT https://prod.example.com/app1/orders -d 'second command for same stub'
+curl https://prod.example.com/app1/notes -H 'Content-Type: text/plain' -d 'a.b*'
+curl https://prod.example.com/other/orders
+curl -u admin:secret https://prod.example.com/app1/ping`)
+	if err != nil {
+		t.Fatalf("Parse error: %s", err)
+	}
+
+	store, target := testStore(t)
+	response := stubs.ServiceStub{Code: 201, Data: `{"ok": true}`, Headers: map[string]string{"Content-Type": "application/json"}}
+	imported, skipped, err := importCurl(store, target, commands, "/app1", response)
+	if err != nil {
+		t.Fatalf("importCurl error: %s", err)
+	}
+	if imported != 4 || skipped != 2 {
+		t.Errorf("imported %d, skipped %d, want 4 and 2", imported, skipped)
+	}
+
+	saved := storedStubs(t, store, target)
+	want := map[string]*stubs.RequestMatch{
+		"POST /orders#full=1&id=7": {
+			Query: map[string]stubs.ValueMatcher{"full": {Equals: "1"}, "id": {Equals: "7"}},
+			Body:  []stubs.BodyMatcher{{EqualToJson: map[string]interface{}{"qty": 2, "sku": "a"}}},
+		},
+		"PUT /orders": {
+			Body: []stubs.BodyMatcher{{Form: map[string]stubs.ValueMatcher{"qty": {Equals: "2"}, "sku": {Equals: "a b"}}}},
+		},
+		"POST /notes": {
+			Body: []stubs.BodyMatcher{{Regex: `^a\.b\*$`}},
+		},
+		"GET /ping": nil,
+	}
+	if !reflect.DeepEqual(keys(saved), []string{"GET /ping", "POST /notes", "POST /orders#full=1&id=7", "PUT /orders"}) {
+		t.Fatalf("saved stubs %v", keys(saved))
+	}
+	for key, match := range want {
+		stub := saved[key]
+		if stub.Code != 201 || stub.Data != response.Data || !reflect.DeepEqual(stub.Headers, response.Headers) {
+			t.Errorf("%s: response %d %q %v, want given response", key, stub.Code, stub.Data, stub.Headers)
+		}
+		if !reflect.DeepEqual(stub.Match, match) {
+			t.Errorf("%s: match %+v, want %+v", key, stub.Match, match)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/journal"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type journalEntryKey struct{}
+
+// redactedValue Replacement of credential values in journal
+const redactedValue = "[redacted]"
+
+// credentialHeaders Headers which values are not kept in journal, other headers and query params
+// with token, secret, password or api key in name are not kept as well
+var credentialHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// isCredential Check header or query param name looks like it carries credentials
+func isCredential(name string) bool {
+	if credentialHeaders[http.CanonicalHeaderKey(name)] {
+		return true
+	}
+
+	name = strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+	for _, part := range []string{"token", "secret", "password", "apikey"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactValues Copy of values with credential values replaced. Authorization scheme is kept
+func redactValues(values map[string][]string) map[string][]string {
+	redacted := make(map[string][]string, len(values))
+	for name, vs := range values {
+		if !isCredential(name) {
+			redacted[name] = append([]string(nil), vs...)
+			continue
+		}
+
+		redacted[name] = make([]string, len(vs))
+		for i, v := range vs {
+			redacted[name][i] = redactedValue
+			if scheme, _, ok := strings.Cut(v, " "); ok && strings.HasSuffix(http.CanonicalHeaderKey(name), "Authorization") {
+				redacted[name][i] = scheme + " " + redactedValue
+			}
+		}
+	}
+
+	return redacted
+}
+
+// journalWriter Response writer remembering response status for journal. Response headers with
+// credentials redacted and body beginning are captured for live feed if capture size is set
+type journalWriter struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+	capture  int
+	header   http.Header
+	body     bytes.Buffer
+	cut      bool
+}
+
+// setStatus Remember status and headers of response at the moment it is sent
+func (jw *journalWriter) setStatus(code int) {
+	if jw.status != 0 {
+		return
+	}
+	jw.status = code
+	if jw.capture > 0 {
+		jw.header = redactValues(jw.ResponseWriter.Header())
+	}
+}
+
+func (jw *journalWriter) WriteHeader(code int) {
+	jw.setStatus(code)
+	jw.ResponseWriter.WriteHeader(code)
+}
+
+func (jw *journalWriter) Write(b []byte) (int, error) {
+	jw.setStatus(http.StatusOK)
+	if jw.capture > 0 && !jw.cut {
+		n := jw.capture - jw.body.Len()
+		if n > len(b) {
+			n = len(b)
+		}
+		jw.body.Write(b[:n])
+		jw.cut = n < len(b)
+	}
+	return jw.ResponseWriter.Write(b)
+}
+
+func (jw *journalWriter) Flush() {
+	if f, ok := jw.ResponseWriter.(http.Flusher); ok {
+		jw.setStatus(http.StatusOK)
+		f.Flush()
+	}
+}
+
+func (jw *journalWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := jw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	jw.hijacked = true
+	return hj.Hijack()
+}
+
+func (jw *journalWriter) Unwrap() http.ResponseWriter {
+	return jw.ResponseWriter
+}
+
+// newJournalEntry Journal entry for request to target, body is read and restored for handler.
+// Body which is not UTF-8 text is kept base64 encoded, credentials in headers and query are redacted
+func newJournalEntry(r *http.Request, target string, maxBody int) *journal.Entry {
+	entry := &journal.Entry{
+		Time:    time.Now(),
+		Target:  target,
+		Method:  r.Method,
+		Path:    "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, target), "/"),
+		Query:   redactValues(r.URL.Query()),
+		Headers: redactValues(r.Header),
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return entry
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return entry
+	}
+
+	entry.Body, entry.BodyEncoding, entry.BodyTruncated = encodeJournalBody(body, maxBody)
+
+	return entry
+}
+
+// encodeJournalBody Body cut to max size, base64 encoded if it is not UTF-8 text
+func encodeJournalBody(body []byte, maxBody int) (string, string, bool) {
+	truncated := false
+	if len(body) > maxBody {
+		body = body[:maxBody]
+		truncated = true
+	}
+	if utf8.Valid(body) {
+		return string(body), "", truncated
+	}
+
+	return base64.StdEncoding.EncodeToString(body), stubs.EncodingBase64, truncated
+}
+
+// withJournalEntry Request context carrying journal entry filled by request handler
+func withJournalEntry(r *http.Request, entry *journal.Entry) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), journalEntryKey{}, entry))
+}
+
+// markStubbed Note stub which served request in request journal entry
+func markStubbed(r *http.Request, key stubs.StubKey) {
+	if entry, ok := r.Context().Value(journalEntryKey{}).(*journal.Entry); ok {
+		entry.Stubbed = true
+		entry.Stub = key.String()
+	}
+}
+
+// recordJournalEntry Complete entry with response status and duration and add it to journal.
+// Request failed with panic has 500 status, upgraded connection 101 one
+func recordJournalEntry(requestJournal journal.Journal, entry *journal.Entry, jw *journalWriter, completed bool) {
+	entry.Duration = float64(time.Since(entry.Time).Microseconds()) / 1000
+	entry.Status = jw.status
+	switch {
+	case !completed && entry.Status == 0:
+		entry.Status = http.StatusInternalServerError
+	case jw.hijacked && entry.Status == 0 && entry.Headers.Get("Upgrade") != "":
+		entry.Status = http.StatusSwitchingProtocols
+	case !jw.hijacked && entry.Status == 0:
+		entry.Status = http.StatusOK
+	}
+
+	if err := requestJournal.Record(entry); err != nil {
+		log.Printf("Can`t record %s %s to journal: %s", entry.Method, entry.Path, err)
+	}
+}
+
+// publishExchange Send recorded request with captured response to live feed
+func publishExchange(trafficFeed *journal.Feed, entry *journal.Entry, jw *journalWriter) {
+	if jw.capture <= 0 {
+		return
+	}
+
+	resp := journal.Response{Headers: jw.header, BodyTruncated: jw.cut}
+	if resp.Headers == nil {
+		resp.Headers = http.Header{}
+	}
+	// Captured body does not exceed capture size
+	resp.Body, resp.BodyEncoding, _ = encodeJournalBody(jw.body.Bytes(), jw.capture)
+
+	trafficFeed.Publish(journal.Exchange{Entry: *entry, Response: resp})
+}
+
+func JournalApiHandler(requestJournal journal.Journal) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			filter, err := journal.ParseFilter(r.URL.Query())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			entries, err := requestJournal.Entries(filter)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(entries)
+			w.Write(resp)
+
+		case "DELETE":
+			if err := requestJournal.Reset(); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				http.Error(w, "", http.StatusOK)
+			}
+		}
+	}
+
+	return fn
+}
+
+func VerifyApiHandler(requestJournal journal.Journal) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			var verification journal.Verification
+			if err := json.NewDecoder(r.Body).Decode(&verification); err != nil {
+				http.Error(w, "Request data not valid", http.StatusBadRequest)
+				return
+			}
+
+			result, err := journal.Verify(requestJournal, verification)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Verification not valid: %s", err), http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(result)
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
+
+func LiveJournalHandler(trafficFeed *journal.Feed) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			filter, err := journal.ParseFilter(r.URL.Query())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			flusher, ok := w.(http.Flusher)
+			if !ok {
+				http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+				return
+			}
+
+			exchanges, cancel := trafficFeed.Subscribe(filter)
+			defer cancel()
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			flusher.Flush()
+
+			for {
+				select {
+				case <-r.Context().Done():
+					return
+				case e := <-exchanges:
+					data, _ := json.Marshal(e)
+					event := stubs.SSEEvent{ID: strconv.FormatInt(e.ID, 10), Event: "exchange", Data: string(data)}
+					if _, err := w.Write([]byte(formatEvent(event))); err != nil {
+						return
+					}
+					flusher.Flush()
+				}
+			}
+		}
+	}
+
+	return fn
+}
@@ -0,0 +1,181 @@
+package routes
+
+import (
+	"github.com/alexedwards/scs/v2"
+	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/journal"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
+	goji "goji.io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRouter Router with single /app1 target, file stub storage and memory journal
+func testRouter(t *testing.T, authEnabled bool) (*goji.Mux, journal.Journal) {
+	t.Helper()
+
+	var cfg config.StubRouterConfig
+	cfg.Targets = map[string]string{"/app1": "http://127.0.0.1:1"}
+	cfg.Auth.Enabled = authEnabled
+	cfg.Journal.Size = 10
+	cfg.Journal.MaxBody = 1024
+	cfg.UnmatchedCode = http.StatusNotFound
+
+	targetRegistry, err := targets.NewRegistry(&cfg)
+	if err != nil {
+		t.Fatalf("NewRegistry error: %s", err)
+	}
+	requestJournal := &journal.MemoryJournal{Size: cfg.Journal.Size}
+	if err = requestJournal.InitJournal(&cfg); err != nil {
+		t.Fatalf("InitJournal error: %s", err)
+	}
+	stubStore := &stubs.FileStubStorage{FsPath: t.TempDir()}
+
+	return Routes(&cfg, scs.New(), stubStore, targetRegistry, requestJournal), requestJournal
+}
+
+func TestRedactValues(t *testing.T) {
+	values := map[string][]string{
+		"Authorization":       {"Bearer eyJhbGciOi.x.y"},
+		"Proxy-Authorization": {"Basic dXNlcjpwdw=="},
+		"Cookie":              {"sessid=abc; theme=dark"},
+		"Set-Cookie":          {"sessid=abc; HttpOnly"},
+		"X-Api-Key":           {"k1", "k2"},
+		"X-Auth-Token":        {"t"},
+		"access_token":        {"q"},
+		"Accept":              {"application/json"},
+		"page":                {"2"},
+	}
+
+	want := map[string][]string{
+		"Authorization":       {"Bearer [redacted]"},
+		"Proxy-Authorization": {"Basic [redacted]"},
+		"Cookie":              {"[redacted]"},
+		"Set-Cookie":          {"[redacted]"},
+		"X-Api-Key":           {"[redacted]", "[redacted]"},
+		"X-Auth-Token":        {"[redacted]"},
+		"access_token":        {"[redacted]"},
+		"Accept":              {"application/json"},
+		"page":                {"2"},
+	}
+	if got := redactValues(values); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactValues = %v, want %v", got, want)
+	}
+	if values["Authorization"][0] != "Bearer eyJhbGciOi.x.y" {
+		t.Error("source values changed")
+	}
+}
+
+func TestNewJournalEntry(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/app1/orders?id=1&api_key=secret", strings.NewReader("data"))
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Cookie", "sessid=secret")
+	r.Header.Set("Accept", "text/plain")
+
+	entry := newJournalEntry(r, "/app1", 2)
+	if entry.Path != "/orders" || entry.Body != "da" || !entry.BodyTruncated {
+		t.Errorf("entry = %+v", entry)
+	}
+	if got := entry.Headers.Get("Authorization") + entry.Headers.Get("Cookie") + entry.Query.Get("api_key"); strings.Contains(got, "secret") {
+		t.Errorf("credentials kept in journal: %q", got)
+	}
+	if entry.Headers.Get("Accept") != "text/plain" || entry.Query.Get("id") != "1" {
+		t.Errorf("plain values not kept: %v %v", entry.Headers, entry.Query)
+	}
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		t.Error("request headers changed")
+	}
+}
+
+func TestRedirectJournaled(t *testing.T) {
+	router, requestJournal := testRouter(t, false)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app1", nil))
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("status = %d, want 301", w.Code)
+	}
+
+	entries, err := requestJournal.Entries(journal.Filter{})
+	if err != nil {
+		t.Fatalf("Entries error: %s", err)
+	}
+	if len(entries) != 1 || entries[0].Target != "/app1" || entries[0].Path != "/" || entries[0].Status != http.StatusMovedPermanently {
+		t.Errorf("journal entries = %+v, want redirect entry", entries)
+	}
+}
+
+func TestApiAuth(t *testing.T) {
+	router, _ := testRouter(t, true)
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/journalapi/", nil),
+		httptest.NewRequest(http.MethodDelete, "/journalapi/", nil),
+		httptest.NewRequest(http.MethodPost, "/journalapi/verify", strings.NewReader("{}")),
+		httptest.NewRequest(http.MethodGet, "/stubapi/unmatched?target=/app1", nil),
+		httptest.NewRequest(http.MethodGet, "/stubapi/files?target=http://127.0.0.1:1&name=a.png", nil),
+		httptest.NewRequest(http.MethodPost, "/stubapi/files?target=http://127.0.0.1:1&name=a.png", strings.NewReader("x")),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/login" {
+			t.Errorf("%s %s: status %d, location %q, want login redirect", r.Method, r.URL.Path, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestPublishExchangeRedacted(t *testing.T) {
+	trafficFeed := journal.NewFeed()
+	exchanges, cancel := trafficFeed.Subscribe(journal.Filter{})
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	jw := &journalWriter{ResponseWriter: w, capture: 4}
+	jw.Header().Set("Set-Cookie", "sessid=secret; HttpOnly")
+	jw.Header().Set("Content-Type", "text/plain")
+	jw.Write([]byte("response body"))
+	jw.Header().Set("X-After", "sent")
+
+	publishExchange(trafficFeed, &journal.Entry{ID: 1, Target: "/app1"}, jw)
+
+	e := <-exchanges
+	if got := e.Response.Headers.Get("Set-Cookie"); got != redactedValue {
+		t.Errorf("live Set-Cookie = %q, want redacted", got)
+	}
+	if e.Response.Headers.Get("Content-Type") != "text/plain" || e.Response.Headers.Get("X-After") != "" {
+		t.Errorf("live headers = %v, want headers at the moment response is sent", e.Response.Headers)
+	}
+	if e.Response.Body != "resp" || !e.Response.BodyTruncated {
+		t.Errorf("live body %q, truncated %t, want cut to capture size", e.Response.Body, e.Response.BodyTruncated)
+	}
+	if w.Header().Get("Set-Cookie") != "sessid=secret; HttpOnly" || w.Body.String() != "response body" {
+		t.Error("response sent to client changed")
+	}
+}
+
+func TestLiveAuth(t *testing.T) {
+	router, _ := testRouter(t, true)
+
+	for _, path := range []string{"/journalapi/live", "/static/inspector.html"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/login" {
+			t.Errorf("%s: status %d, location %q, want login redirect", path, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	// File server must not serve inspector page by other path
+	for _, path := range []string{"/static/./inspector.html", "/static//inspector.html", "/static/css/../inspector.html"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = path
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != inspectorPage {
+			t.Errorf("%s: status %d, location %q, want inspector page redirect", path, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
@@ -1,14 +1,30 @@
 package routes
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/alexedwards/scs/v2"
 	"github.com/overdone/stubrouter/internal/config"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
+	"time"
 )
 
+// sessionResponseWriter Commits session before response is started, so response is not buffered
+// and can be flushed or hijacked
+type sessionResponseWriter struct {
+	http.ResponseWriter
+	r              *http.Request
+	sessionManager *scs.SessionManager
+	committed      bool
+	used           *bool
+}
+
+type sessionUsedKey struct{}
+
 type ErrorViewData struct {
 	Code    int
 	Message string
@@ -48,6 +64,9 @@ func serverErrorMiddleware(next http.Handler) http.Handler {
 func authMiddleware(cfg *config.StubRouterConfig, sessionManager *scs.SessionManager) func(http.Handler) http.Handler {
 	m := func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Auth.Enabled {
+				markSessionUsed(r)
+			}
 			if !cfg.Auth.Enabled || sessionManager.Exists(r.Context(), "userData") {
 				next.ServeHTTP(w, r)
 			} else {
@@ -71,3 +90,101 @@ func logMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(fn)
 }
+
+// commit Save session and write session cookie
+func (sw *sessionResponseWriter) commit() {
+	if sw.committed {
+		return
+	}
+	sw.committed = true
+
+	ctx := sw.r.Context()
+	switch sw.sessionManager.Status(ctx) {
+	case scs.Modified:
+		token, expiry, err := sw.sessionManager.Commit(ctx)
+		if err != nil {
+			log.Printf("Session commit error: %s", err)
+			return
+		}
+		sw.sessionManager.WriteSessionCookie(ctx, sw.ResponseWriter, token, expiry)
+	case scs.Destroyed:
+		sw.sessionManager.WriteSessionCookie(ctx, sw.ResponseWriter, "", time.Time{})
+	case scs.Unmodified:
+		// Response depends on cookie only if session data was read
+		if !*sw.used {
+			return
+		}
+	}
+
+	sw.Header().Add("Vary", "Cookie")
+}
+
+// markSessionUsed Note response depends on session data, so it varies by session cookie
+func markSessionUsed(r *http.Request) {
+	if used, ok := r.Context().Value(sessionUsedKey{}).(*bool); ok {
+		*used = true
+	}
+}
+
+func (sw *sessionResponseWriter) WriteHeader(code int) {
+	sw.commit()
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *sessionResponseWriter) Write(b []byte) (int, error) {
+	sw.commit()
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *sessionResponseWriter) Flush() {
+	sw.commit()
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sw *sessionResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	sw.commit()
+	hj, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	return hj.Hijack()
+}
+
+func (sw *sessionResponseWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// sessionMiddleware Load request session and save it before response is started.
+// Unlike scs LoadAndSave response is not buffered, so streamed and delayed responses reach client as written.
+// Vary: Cookie is set only on responses which read, changed or destroyed session
+func sessionMiddleware(sessionManager *scs.SessionManager) func(http.Handler) http.Handler {
+	m := func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			if cookie, err := r.Cookie(sessionManager.Cookie.Name); err == nil {
+				token = cookie.Value
+			}
+
+			ctx, err := sessionManager.Load(r.Context(), token)
+			if err != nil {
+				sessionManager.ErrorFunc(w, r, err)
+				return
+			}
+
+			used := false
+			sr := r.WithContext(context.WithValue(ctx, sessionUsedKey{}, &used))
+			sw := &sessionResponseWriter{ResponseWriter: w, r: sr, sessionManager: sessionManager, used: &used}
+			next.ServeHTTP(sw, sr)
+
+			// Handler wrote nothing, session still must be saved
+			sw.commit()
+		}
+
+		return http.HandlerFunc(fn)
+	}
+
+	return m
+}
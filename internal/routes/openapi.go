@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/openapi"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var specParamRe = regexp.MustCompile(`\{([^}]*)\}`)
+var nonWordRe = regexp.MustCompile(`\W`)
+
+// specStubPath Stub path template for spec path, parameter names are made valid template variables
+func specStubPath(path string) string {
+	return specParamRe.ReplaceAllStringFunc(path, func(param string) string {
+		name := nonWordRe.ReplaceAllString(param[1:len(param)-1], "_")
+		if name == "" || name[0] >= '0' && name[0] <= '9' {
+			name = "p" + name
+		}
+		return "{" + name + "}"
+	})
+}
+
+// importOpenApi Save stub for every spec operation, operations with invalid stubs are skipped
+func importOpenApi(stubStore stubs.StubStorage, targetUrl *url.URL, mocks []openapi.MockResponse) (int, int, error) {
+	imported, skipped := 0, 0
+	for _, mock := range mocks {
+		key := stubs.NewStubKey(mock.Method, specStubPath(mock.Path), "")
+		stub := stubs.ServiceStub{Code: mock.Status, Data: string(mock.Body), Headers: mock.Headers}
+
+		saved, err := saveImportedStub(stubStore, targetUrl, key, stub)
+		if err != nil {
+			return imported, skipped, err
+		}
+		if saved {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	return imported, skipped, nil
+}
+
+func OpenApiHandler(stubStore stubs.StubStorage) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "POST":
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Request data not valid", http.StatusBadRequest)
+				return
+			}
+
+			doc, err := openapi.Parse(data)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// Servers path is replaced if upstream serves api under other path
+			if q.Has("basePath") {
+				doc.Servers = []openapi.Server{{URL: "/" + strings.Trim(q.Get("basePath"), "/")}}
+			}
+
+			// Operations which response can`t be built are skipped, others are imported
+			mocks, mockErrs := doc.MockResponses()
+			errs := make([]string, 0, len(mockErrs))
+			for _, err := range mockErrs {
+				log.Printf("Can`t build OpenAPI response: %s", err)
+				errs = append(errs, err.Error())
+			}
+
+			imported, skipped, err := importOpenApi(stubStore, targetUrl, mocks)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			skipped += len(errs)
+
+			log.Printf("Imported %d stubs for %s from OpenAPI spec, %d operations skipped", imported, targetUrl, skipped)
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(map[string]interface{}{"imported": imported, "skipped": skipped, "errors": errs})
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
@@ -5,17 +5,25 @@ import (
 	"fmt"
 	"github.com/alexedwards/scs/v2"
 	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/journal"
+	"github.com/overdone/stubrouter/internal/latency"
 	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
 	"goji.io/pat"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func handleProxy(cfg *config.StubRouterConfig, stubStore stubs.StubStorage, sessionManager *scs.SessionManager) http.HandlerFunc {
+// defaultChunkSize Stub body chunk size if chunk latency is set without chunk size, bytes
+const defaultChunkSize = 1024
+
+func handleProxy(cfg *config.StubRouterConfig, stubStore stubs.StubStorage, sessionManager *scs.SessionManager, targetRegistry *targets.Registry) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		path := "/" + pat.Param(r, "route")
 		host := cfg.Targets[path]
@@ -42,25 +50,221 @@ func handleProxy(cfg *config.StubRouterConfig, stubStore stubs.StubStorage, sess
 			log.Panic(fmt.Sprintf("Can`t proxy request to %s", targetUrl))
 		}
 
-		if sm, err := stubStore.GetServiceStubs(r.URL); err != nil || sm == nil {
-			proxy.ServeHTTP(w, r)
-		} else if stub, ok := sm.Service[targetPath]; ok {
-			log.Printf("Get %s response from stub", targetPath)
-			w.WriteHeader(stub.Code)
-			for k, v := range stub.Headers {
-				w.Header().Add(k, v)
+		reqData, err := stubs.NewRequestData(r, targetPath)
+		if err != nil {
+			log.Panic(fmt.Sprintf("Can`t read request to %s", targetUrl))
+		}
+
+		settings, _ := targetRegistry.Get(path)
+		if settings.ValidateUpstream && settings.Contract != nil {
+			proxy.ModifyResponse = checkUpstreamContract(settings.Contract, r.Method, targetPath)
+		}
+
+		switch settings.Mode {
+		case targets.ModePassthrough:
+			serveProxy(w, r, proxy, settings, targetPath)
+			return
+		case targets.ModeRecord:
+			// Ask upstream for uncompressed response to store readable stub data
+			r.Header.Del("Accept-Encoding")
+			proxy.ModifyResponse = chainResponse(proxy.ModifyResponse, recordResponse(stubStore, r.URL, reqData))
+			serveProxy(w, r, proxy, settings, targetPath)
+			return
+		}
+
+		if match := matchStub(stubStore, r.URL, reqData); match != nil {
+			log.Printf("Get %s response from stub %s", targetPath, match.Key)
+			markStubbed(r, match.Key)
+			if err := stubStore.RecordHit(r.URL, match.Key, time.Now()); err != nil {
+				log.Printf("Can`t record stub %s hit: %s", match.Key, err)
 			}
-			time.Sleep(time.Duration(stub.Timeout) * time.Millisecond)
-			w.Write([]byte(stub.Data))
-		} else {
-			proxy.ServeHTTP(w, r)
+			// Stub bandwidth limit takes precedence over target one
+			bandwidth := settings.Bandwidth
+			if match.Stub.Bandwidth > 0 {
+				bandwidth = match.Stub.Bandwidth
+			}
+			writeStub(throttle(w, r, bandwidth), r, stubStore, r.URL, match, reqData)
+			return
 		}
+		markUnmatched(r, stubStore, r.URL, reqData)
+
+		if settings.Mode == targets.ModeStub {
+			msg := fmt.Sprintf("Stub for %s %s not found", r.Method, targetPath)
+			http.Error(w, msg, settings.UnmatchedCode)
+			return
+		}
+
+		serveProxy(w, r, proxy, settings, targetPath)
 	}
 
 	return fn
 }
 
-func RouteHandler(cfg *config.StubRouterConfig, stubStore stubs.StubStorage, sessionManager *scs.SessionManager) http.HandlerFunc {
+// scenarioAttempts Times request is matched again when other request moved scenario of its stub meanwhile
+const scenarioAttempts = 3
+
+// matchStub Find stub for request with current scenario states, pick stub sequence response and move
+// stub scenario to new state. Returns nil if stub not found or its sequence is over
+func matchStub(stubStore stubs.StubStorage, host *url.URL, reqData *stubs.RequestData) *stubs.StubMatch {
+	sm, err := stubStore.GetServiceStubs(host)
+	if err != nil || sm == nil {
+		return nil
+	}
+
+	for attempt := 0; attempt < scenarioAttempts; attempt++ {
+		var states map[string]string
+		if sm.HasScenarios() {
+			if states, err = stubStore.GetScenarioStates(host); err != nil {
+				log.Panic(fmt.Sprintf("Can`t get scenario states for %s", host))
+			}
+		}
+
+		match, ok := sm.Match(reqData, states)
+		if !ok {
+			return nil
+		}
+
+		if len(match.Stub.Responses) > 0 {
+			n, err := stubStore.NextSequenceIndex(host, match.Key)
+			if err != nil {
+				log.Panic(fmt.Sprintf("Can`t get stub %s sequence counter", match.Key))
+			}
+			if match.Stub, ok = match.Stub.SequenceResponse(n); !ok {
+				log.Printf("Stub %s sequence is over", match.Key)
+				return nil
+			}
+		}
+
+		if moveScenario(stubStore, host, match.Stub) {
+			return match
+		}
+		log.Printf("Scenario %s left state %s while matching, match again", match.Stub.Scenario, match.Stub.RequiredState)
+	}
+
+	return nil
+}
+
+// moveScenario Switch scenario to stub new state before stub response. State is changed only if scenario
+// is still in stub required state, so concurrent requests can`t both take the same transition.
+// Returns false if other request moved scenario first
+func moveScenario(stubStore stubs.StubStorage, host *url.URL, stub stubs.ServiceStub) bool {
+	if stub.Scenario == "" || stub.NewState == "" {
+		return true
+	}
+
+	moved, err := stubStore.MoveScenario(host, stub.Scenario, stub.RequiredState, stub.NewState)
+	if err != nil {
+		log.Printf("Can`t set scenario %s state %s: %s", stub.Scenario, stub.NewState, err)
+		return true
+	}
+	if moved {
+		log.Printf("Scenario %s moved to state %s", stub.Scenario, stub.NewState)
+	}
+
+	return moved
+}
+
+// writeStub Write stub response rendered with request data. Successful binary responses
+// are served with range requests support
+func writeStub(w http.ResponseWriter, r *http.Request, stubStore stubs.StubStorage, host *url.URL, match *stubs.StubMatch, reqData *stubs.RequestData) {
+	stub := match.Stub
+	data, headers, err := match.Render(reqData)
+	if err != nil {
+		log.Printf("Can`t render stub %s: %s", match.Key, err)
+		http.Error(w, fmt.Sprintf("Stub %s render error: %s", match.Key, err), http.StatusInternalServerError)
+		return
+	}
+
+	body, modTime, err := stubs.OpenBody(stubStore, host, stub, data)
+	if err != nil {
+		log.Printf("Can`t open stub %s body: %s", match.Key, err)
+		http.Error(w, fmt.Sprintf("Stub %s body error: %s", match.Key, err), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	// Client gone while waiting, nobody to respond to
+	if err = latency.Sleep(r.Context(), stub.FirstByteLatency()); err != nil {
+		return
+	}
+
+	if stub.Fault != "" {
+		content, _ := io.ReadAll(body)
+		if err = writeFault(w, r, stub, string(content), headers); err != nil {
+			log.Printf("Stub %s fault %s error: %s", match.Key, stub.Fault, err)
+		}
+		return
+	}
+
+	if stub.SSE != nil {
+		writeEventStream(w, r, stub, headers)
+		return
+	}
+
+	if stub.WebSocket != nil {
+		serveWebSocket(w, r, match.Key.String(), stub.WebSocket)
+		return
+	}
+
+	for k, v := range headers {
+		w.Header().Add(k, v)
+	}
+
+	if stub.IsBinary() && stub.Code == http.StatusOK && stub.ChunkLatency == nil {
+		http.ServeContent(w, r, stub.BodyFile, modTime, body)
+		return
+	}
+
+	size, err := body.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = body.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	w.WriteHeader(stub.Code)
+	writeChunked(w, r, body, stub.ChunkSize, stub.ChunkLatency)
+}
+
+// writeChunked Write body by chunks with latency between chunks. Body is written at once without chunk latency
+func writeChunked(w http.ResponseWriter, r *http.Request, body io.Reader, chunkSize int, chunkLatency *latency.Profile) {
+	if chunkLatency == nil {
+		_, _ = io.Copy(w, body)
+		return
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	flusher, _ := w.(http.Flusher)
+
+	chunk := make([]byte, chunkSize)
+	for first := true; ; first = false {
+		n, err := io.ReadFull(body, chunk)
+		if n == 0 {
+			return
+		}
+
+		if !first {
+			if err := latency.Sleep(r.Context(), chunkLatency.Sample()); err != nil {
+				return
+			}
+		}
+		if _, err := w.Write(chunk[:n]); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func RouteHandler(cfg *config.StubRouterConfig, stubStore stubs.StubStorage, sessionManager *scs.SessionManager, targetRegistry *targets.Registry, requestJournal journal.Journal, trafficFeed *journal.Feed) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		forkPath := "/" + pat.Param(r, "route")
 
@@ -70,12 +274,25 @@ func RouteHandler(cfg *config.StubRouterConfig, stubStore stubs.StubStorage, ses
 			return
 		}
 
+		entry := newJournalEntry(r, forkPath, cfg.Journal.MaxBody)
+		jw := &journalWriter{ResponseWriter: w}
+		if trafficFeed.Active() {
+			jw.capture = cfg.Journal.MaxBody
+		}
+		completed := false
+		// Deferred to record requests failed with panic as well
+		defer func() {
+			recordJournalEntry(requestJournal, entry, jw, completed)
+			publishExchange(trafficFeed, entry, jw)
+		}()
+
 		if forkPath == r.URL.Path {
 			// Go to index
-			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			http.Redirect(jw, r, r.URL.Path+"/", http.StatusMovedPermanently)
 		} else {
-			handleProxy(cfg, stubStore, sessionManager)(w, r)
+			handleProxy(cfg, stubStore, sessionManager, targetRegistry)(jw, withJournalEntry(r, entry))
 		}
+		completed = true
 	}
 
 	return fn
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"github.com/overdone/stubrouter/internal/stubs"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestMatchStubScenarioOnce(t *testing.T) {
+	store := &stubs.FileStubStorage{FsPath: t.TempDir()}
+	host, _ := url.Parse("http://scenario.test:8080")
+	saved := map[string]stubs.ServiceStub{
+		"GET /order#pending": {Code: 200, Data: "pending", Scenario: "order", RequiredState: stubs.ScenarioStarted, NewState: "shipped"},
+		"GET /order#shipped": {Code: 200, Data: "shipped", Scenario: "order", RequiredState: "shipped"},
+	}
+	for k, stub := range saved {
+		if err := store.SaveServiceStub(host, stubs.ParseStubKey(k), stub); err != nil {
+			t.Fatalf("SaveServiceStub error: %s", err)
+		}
+	}
+	defer store.ResetScenarios(host)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	served := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			match := matchStub(store, host, &stubs.RequestData{Method: "GET", Path: "/order"})
+			mu.Lock()
+			defer mu.Unlock()
+			if match == nil {
+				served["none"]++
+			} else {
+				served[match.Stub.Data]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Only one of concurrent requests takes the transition, others see the new state
+	if served["pending"] != 1 || served["shipped"]+served["none"] != 19 {
+		t.Errorf("served %v, want pending once", served)
+	}
+	if states, _ := store.GetScenarioStates(host); states["order"] != "shipped" {
+		t.Errorf("scenario states %v, want order shipped", states)
+	}
+}
+
+func TestMoveScenario(t *testing.T) {
+	store := &stubs.FileStubStorage{}
+	host, _ := url.Parse("http://move.test:8080")
+	defer store.ResetScenarios(host)
+
+	tests := []struct {
+		name  string
+		stub  stubs.ServiceStub
+		moved bool
+		state string
+	}{
+		{"no scenario", stubs.ServiceStub{}, true, stubs.ScenarioStarted},
+		{"from started", stubs.ServiceStub{Scenario: "s", RequiredState: stubs.ScenarioStarted, NewState: "a"}, true, "a"},
+		{"state already left", stubs.ServiceStub{Scenario: "s", RequiredState: stubs.ScenarioStarted, NewState: "b"}, false, "a"},
+		{"any state", stubs.ServiceStub{Scenario: "s", NewState: "c"}, true, "c"},
+		{"no new state", stubs.ServiceStub{Scenario: "s", RequiredState: "c"}, true, "c"},
+	}
+
+	for _, tt := range tests {
+		if moved := moveScenario(store, host, tt.stub); moved != tt.moved {
+			t.Errorf("%s: moved %t, want %t", tt.name, moved, tt.moved)
+		}
+		states, _ := store.GetScenarioStates(host)
+		if state := stubs.ScenarioState(states, "s"); state != tt.state {
+			t.Errorf("%s: state %s, want %s", tt.name, state, tt.state)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// skipRecordHeaders Response headers not stored in recorded stubs
+var skipRecordHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Vary":              true,
+}
+
+// streamingContentTypes Response content types of endless streams, such responses are not recorded
+var streamingContentTypes = map[string]bool{
+	"text/event-stream":         true,
+	"application/x-ndjson":      true,
+	"multipart/x-mixed-replace": true,
+}
+
+// recordingBody Response body passed to client as it is read and saved when it is read to the end.
+// Body of response interrupted by client is not saved
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func(body []byte)
+}
+
+func (rb *recordingBody) Read(p []byte) (int, error) {
+	n, err := rb.ReadCloser.Read(p)
+	rb.buf.Write(p[:n])
+	if err == io.EOF && rb.done != nil {
+		rb.done(rb.buf.Bytes())
+		rb.done = nil
+	}
+
+	return n, err
+}
+
+// recordedStubKey Stub key for recorded request. Requests with query are stored as variant named
+// by query string, which matches same query params only
+func recordedStubKey(reqData *stubs.RequestData) (stubs.StubKey, *stubs.RequestMatch) {
+	if len(reqData.Query) == 0 {
+		return stubs.NewStubKey(reqData.Method, reqData.Path, ""), nil
+	}
+
+	names := make([]string, 0, len(reqData.Query))
+	for name := range reqData.Query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	match := &stubs.RequestMatch{Query: make(map[string]stubs.ValueMatcher)}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := reqData.Query.Get(name)
+		match.Query[name] = stubs.ValueMatcher{Equals: value}
+		parts = append(parts, fmt.Sprintf("%s=%s", url.QueryEscape(name), url.QueryEscape(value)))
+	}
+
+	return stubs.NewStubKey(reqData.Method, reqData.Path, strings.Join(parts, "&")), match
+}
+
+// recordedStub Stub for upstream response, body which is not UTF-8 text is stored base64 encoded
+func recordedStub(status int, header http.Header, body []byte) stubs.ServiceStub {
+	headers := make(map[string]string)
+	for k, v := range header {
+		switch {
+		case skipRecordHeaders[k]:
+		// Cookies can`t be joined, their expiration dates have commas. Stub header has single value
+		case k == "Set-Cookie":
+			headers[k] = v[0]
+		default:
+			headers[k] = strings.Join(v, ", ")
+		}
+	}
+
+	stub := stubs.ServiceStub{Code: status, Data: string(body), Headers: headers}
+	if !utf8.Valid(body) {
+		stub.Data = base64.StdEncoding.EncodeToString(body)
+		stub.Encoding = stubs.EncodingBase64
+	}
+
+	return stub
+}
+
+// recordResponse Save upstream response as stub for proxied request
+func recordResponse(stubStore stubs.StubStorage, host *url.URL, reqData *stubs.RequestData) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		// Upgraded connection and event stream bodies are streams, they can`t be stored as stub
+		if resp.StatusCode == http.StatusSwitchingProtocols {
+			return nil
+		}
+		if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); streamingContentTypes[mediaType] {
+			log.Printf("Skip recording %s %s stream", reqData.Path, mediaType)
+			return nil
+		}
+
+		// Body is not read here, slow responses reach client as upstream sends them
+		status, header := resp.StatusCode, resp.Header.Clone()
+		resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte) {
+			key, match := recordedStubKey(reqData)
+			stub := recordedStub(status, header, body)
+			stub.Match = match
+			if err := stubStore.SaveServiceStub(host, key, stub); err != nil {
+				log.Printf("Can`t record stub %s: %s", key, err)
+			} else {
+				log.Printf("Recorded %s response to stub %s", reqData.Path, key)
+			}
+		}}
+
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRecordedStub(t *testing.T) {
+	header := http.Header{
+		"Content-Type":   {"application/json"},
+		"Content-Length": {"2"},
+		"Cache-Control":  {"no-cache", "no-store"},
+		"Set-Cookie": {
+			"a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT",
+			"b=2; Expires=Thu, 22 Oct 2026 07:28:00 GMT",
+		},
+	}
+
+	stub := recordedStub(http.StatusCreated, header, []byte("{}"))
+	want := map[string]string{
+		"Content-Type":  "application/json",
+		"Cache-Control": "no-cache, no-store",
+		"Set-Cookie":    "a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT",
+	}
+	if stub.Code != http.StatusCreated || stub.Data != "{}" || !reflect.DeepEqual(stub.Headers, want) {
+		t.Errorf("recordedStub = %d %q %v, want headers %v", stub.Code, stub.Data, stub.Headers, want)
+	}
+
+	binary := recordedStub(http.StatusOK, http.Header{}, []byte{0xff, 0x00})
+	if binary.Encoding != "base64" || binary.Data != "/wA=" {
+		t.Errorf("binary stub data %q encoding %q, want base64", binary.Data, binary.Encoding)
+	}
+}
@@ -3,14 +3,19 @@ package routes
 import (
 	"github.com/alexedwards/scs/v2"
 	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/journal"
 	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
 	goji "goji.io"
 	"goji.io/pat"
 )
 
-func Routes(cfg *config.StubRouterConfig, sessionManager *scs.SessionManager, stubStore stubs.StubStorage) *goji.Mux {
+func Routes(cfg *config.StubRouterConfig, sessionManager *scs.SessionManager, stubStore stubs.StubStorage, targetRegistry *targets.Registry, requestJournal journal.Journal) *goji.Mux {
 	router := goji.NewMux()
+	trafficFeed := journal.NewFeed()
 
+	// Traffic inspector shows journal, it is served to authorized users only
+	router.Handle(pat.New(inspectorPage), authMiddleware(cfg, sessionManager)(InspectorHandler()))
 	router.HandleFunc(pat.New("/static/*"), StaticHandler())
 
 	router.Handle(pat.Get("/"), authMiddleware(cfg, sessionManager)(RootHandler(cfg, sessionManager)))
@@ -21,12 +26,31 @@ func Routes(cfg *config.StubRouterConfig, sessionManager *scs.SessionManager, st
 
 	router.Handle(pat.Get("/logout"), LogoutHandler(sessionManager))
 
-	router.Handle(pat.New("/stubapi/*"), StubApiHandler(stubStore))
-
-	routHandler := authMiddleware(cfg, sessionManager)(RouteHandler(cfg, stubStore, sessionManager))
+	// Journal keeps request headers and bodies, it is shown to authorized users only
+	journalAuth := authMiddleware(cfg, sessionManager)
+
+	router.Handle(pat.New("/stubapi/scenarios"), ScenarioApiHandler(stubStore))
+	router.Handle(pat.New("/stubapi/sequences"), SequenceApiHandler(stubStore))
+	router.Handle(pat.New("/stubapi/coverage"), CoverageApiHandler(stubStore))
+	// Body files are kept in stubs dir, they are changed by authorized users only
+	router.Handle(pat.New("/stubapi/files"), authMiddleware(cfg, sessionManager)(BodyFileApiHandler(stubStore)))
+	router.Handle(pat.New("/stubapi/har"), HarApiHandler(stubStore))
+	router.Handle(pat.New("/stubapi/openapi"), OpenApiHandler(stubStore))
+	router.Handle(pat.New("/stubapi/postman"), PostmanApiHandler(stubStore))
+	router.Handle(pat.New("/stubapi/curl"), CurlApiHandler(stubStore))
+	router.Handle(pat.New("/stubapi/unmatched"), journalAuth(UnmatchedApiHandler(targetRegistry, requestJournal)))
+	router.Handle(pat.New("/stubapi/*"), StubApiHandler(stubStore, targetRegistry))
+	router.Handle(pat.New("/targetapi/contract"), ContractApiHandler(targetRegistry))
+	router.Handle(pat.New("/targetapi/*"), TargetApiHandler(targetRegistry))
+	router.Handle(pat.New("/journalapi/verify"), journalAuth(VerifyApiHandler(requestJournal)))
+	router.Handle(pat.New("/journalapi/live"), journalAuth(LiveJournalHandler(trafficFeed)))
+	router.Handle(pat.New("/journalapi/*"), journalAuth(JournalApiHandler(requestJournal)))
+
+	routHandler := authMiddleware(cfg, sessionManager)(RouteHandler(cfg, stubStore, sessionManager, targetRegistry, requestJournal, trafficFeed))
 	router.Handle(pat.New("/:route"), routHandler)
 	router.Handle(pat.New("/:route/*"), routHandler)
 
+	router.Use(sessionMiddleware(sessionManager))
 	router.Use(serverErrorMiddleware)
 	router.Use(logMiddleware)
 
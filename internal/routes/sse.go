@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"fmt"
+	"github.com/overdone/stubrouter/internal/latency"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// formatEvent Serialize event to text/event-stream format, multiline data is sent as several data fields
+func formatEvent(e stubs.SSEEvent) string {
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// writeEventStream Play stub event stream script until it ends or client disconnects
+func writeEventStream(w http.ResponseWriter, r *http.Request, stub stubs.ServiceStub, headers map[string]string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(stub.Code)
+	flusher.Flush()
+
+	for run := 0; stub.SSE.Repeat < 0 || run <= stub.SSE.Repeat; run++ {
+		for _, e := range stub.SSE.Events {
+			if err := latency.Sleep(r.Context(), time.Duration(e.Delay)*time.Millisecond); err != nil {
+				return
+			}
+			if _, err := w.Write([]byte(formatEvent(e))); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
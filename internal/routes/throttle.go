@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"bufio"
+	"github.com/overdone/stubrouter/internal/latency"
+	"net"
+	"net/http"
+	"time"
+)
+
+// throttleSlices Number of writes per second, smaller slices give smoother transfer
+const throttleSlices = 10
+
+// throttledWriter Response writer pacing body writes to bandwidth limit
+type throttledWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	rate    int
+	start   time.Time
+	written int64
+}
+
+// throttle Limit response bandwidth to rate bytes per second, zero rate means no limit
+func throttle(w http.ResponseWriter, r *http.Request, rate int) http.ResponseWriter {
+	if rate <= 0 {
+		return w
+	}
+
+	return &throttledWriter{ResponseWriter: w, r: r, rate: rate}
+}
+
+func (tw *throttledWriter) Write(b []byte) (int, error) {
+	if tw.start.IsZero() {
+		tw.start = time.Now()
+	}
+
+	slice := tw.rate / throttleSlices
+	if slice == 0 {
+		slice = 1
+	}
+
+	total := 0
+	for len(b) > 0 {
+		n := slice
+		if n > len(b) {
+			n = len(b)
+		}
+
+		written, err := tw.ResponseWriter.Write(b[:n])
+		total += written
+		tw.written += int64(written)
+		if err != nil {
+			return total, err
+		}
+		if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		b = b[n:]
+
+		// Wait until written amount fits to rate
+		due := time.Duration(float64(tw.written) / float64(tw.rate) * float64(time.Second))
+		if err = latency.Sleep(tw.r.Context(), due-time.Since(tw.start)); err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (tw *throttledWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *throttledWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := tw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	return hj.Hijack()
+}
+
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
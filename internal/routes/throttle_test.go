@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	if throttle(w, r, 0) != w {
+		t.Error("writer without limit is wrapped")
+	}
+
+	tests := []struct {
+		rate int
+		size int
+		min  time.Duration
+	}{
+		// Every slice of rate/10 bytes waits until written amount fits to rate
+		{10000, 2000, 200 * time.Millisecond},
+		{10000, 500, 50 * time.Millisecond},
+		{5, 2, 400 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		w = httptest.NewRecorder()
+		data := bytes.Repeat([]byte("x"), tt.size)
+
+		start := time.Now()
+		n, err := throttle(w, r, tt.rate).Write(data)
+		elapsed := time.Since(start)
+		if n != tt.size || err != nil || !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("rate %d: written %d, error %v", tt.rate, n, err)
+		}
+		if elapsed < tt.min-10*time.Millisecond || elapsed > tt.min+time.Second {
+			t.Errorf("rate %d: %d bytes written in %s, want about %s", tt.rate, tt.size, elapsed, tt.min)
+		}
+		if !w.Flushed {
+			t.Errorf("rate %d: slices not flushed", tt.rate)
+		}
+	}
+}
+
+func TestThrottleCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	tw := throttle(w, r, 100)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	n, err := tw.Write(bytes.Repeat([]byte("x"), 1000))
+	if err == nil || n >= 1000 {
+		t.Errorf("written %d, error %v, want write stopped by canceled request", n, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("write stopped in %s", time.Since(start))
+	}
+}
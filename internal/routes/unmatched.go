@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/journal"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/targets"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// maxStubNearMisses Number of closest stubs kept for unmatched request
+const maxStubNearMisses = 3
+
+// markUnmatched Note in request journal entry that no stub was found for request, with closest stubs
+// of target and reasons they did not match
+func markUnmatched(r *http.Request, stubStore stubs.StubStorage, host *url.URL, reqData *stubs.RequestData) {
+	entry, ok := r.Context().Value(journalEntryKey{}).(*journal.Entry)
+	if !ok {
+		return
+	}
+	entry.Unmatched = true
+
+	sm, err := stubStore.GetServiceStubs(host)
+	if err != nil || sm == nil {
+		return
+	}
+
+	var states map[string]string
+	if sm.HasScenarios() {
+		if states, err = stubStore.GetScenarioStates(host); err != nil {
+			log.Printf("Can`t get scenario states for %s: %s", host, err)
+			return
+		}
+	}
+
+	entry.NearMisses = sm.NearMisses(reqData, states, maxStubNearMisses)
+}
+
+func UnmatchedApiHandler(targetRegistry *targets.Registry, requestJournal journal.Journal) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		targetParam := q.Get("target")
+		targetUrl, err := url.Parse(targetParam)
+		if err != nil || targetParam == "" {
+			http.Error(w, fmt.Sprintf("Target %s not valid", targetParam), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			// Journal targets are router paths, host ones are resolved below
+			q.Del("target")
+			filter, err := journal.ParseFilter(q)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Several target paths can be routed to the same host
+			entries := make([]journal.Entry, 0)
+			for path := range targetRegistry.ForHost(targetUrl) {
+				found, err := requestJournal.Unmatched(path, filter)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Can`t read journal: %s", err), http.StatusInternalServerError)
+					return
+				}
+				entries = append(entries, found...)
+			}
+
+			sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+			if filter.Limit > 0 && len(entries) > filter.Limit {
+				entries = entries[len(entries)-filter.Limit:]
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			resp, _ := json.Marshal(entries)
+			w.Write(resp)
+		}
+	}
+
+	return fn
+}
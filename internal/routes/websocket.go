@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/latency"
+	"github.com/overdone/stubrouter/internal/stubs"
+	"github.com/overdone/stubrouter/internal/websocket"
+	"log"
+	"net/http"
+	"time"
+)
+
+// sendMessages Send script messages with their delays
+func sendMessages(ctx context.Context, conn *websocket.Conn, messages []stubs.WSMessage) error {
+	for _, m := range messages {
+		if err := latency.Sleep(ctx, time.Duration(m.Delay)*time.Millisecond); err != nil {
+			return err
+		}
+
+		payload, err := m.Payload()
+		if err != nil {
+			return err
+		}
+		opcode := byte(websocket.OpText)
+		if m.Type == stubs.WSBinary {
+			opcode = websocket.OpBinary
+		}
+		if err = conn.WriteMessage(opcode, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serveWebSocket Play stub WebSocket script until it closes connection or client disconnects
+func serveWebSocket(w http.ResponseWriter, r *http.Request, key string, script *stubs.WebSocketScript) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Stub %s requires websocket connection: %s", key, err), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	// Request context is not canceled on disconnect after hijack, reader loop cancels script instead
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = sendMessages(ctx, conn, script.OnConnect)
+	}()
+
+	if script.CloseAfter > 0 {
+		go closeAfter(ctx, conn, script)
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		reply, ok := script.Reply(message)
+		if !ok {
+			log.Printf("Stub %s has no reply for websocket message", key)
+			continue
+		}
+		go func() {
+			_ = sendMessages(ctx, conn, reply.Messages)
+		}()
+	}
+}
+
+// closeAfter Close connection with script close code after script delay
+func closeAfter(ctx context.Context, conn *websocket.Conn, script *stubs.WebSocketScript) {
+	if err := latency.Sleep(ctx, time.Duration(script.CloseAfter)*time.Millisecond); err != nil {
+		return
+	}
+
+	code := script.CloseCode
+	if code == 0 {
+		code = websocket.CloseNormal
+	}
+	_ = conn.WriteClose(code, "")
+	// Give client a moment to answer close frame before connection is dropped
+	if latency.Sleep(ctx, time.Second) == nil {
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,172 @@
+package stubs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"github.com/overdone/stubrouter/internal/utils"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EncodingBase64 Stub data encoding for binary bodies
+const EncodingBase64 = "base64"
+
+// nopSeekCloser In-memory body with no-op Close
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// bodyFilesKey Redis key of body file blob
+func bodyFilesKey(host *url.URL, name string) string {
+	return fmt.Sprintf("%s:files:%s", utils.HostToString(host), name)
+}
+
+// bodyFilePath Body file path inside target files dir of FS storage, file name can`t point outside of it
+func (s FileStubStorage) bodyFilePath(host *url.URL, name string) (string, error) {
+	dir := filepath.Join(s.FsPath, "files", utils.HostToString(host))
+	filename := filepath.Join(dir, name)
+	if !strings.HasPrefix(filename, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("body file %s is outside of target files dir", name)
+	}
+
+	return filename, nil
+}
+
+// IsBinary Check stub body is not plain data
+func (s ServiceStub) IsBinary() bool {
+	return s.BodyFile != "" || s.Encoding == EncodingBase64
+}
+
+// validateBody Check body params
+func (s ServiceStub) validateBody() error {
+	if s.BodyFile != "" && !fs.ValidPath(s.BodyFile) {
+		return fmt.Errorf("body file %s must be relative path without . and .. elements", s.BodyFile)
+	}
+
+	switch s.Encoding {
+	case "":
+	case EncodingBase64:
+		if s.BodyFile != "" {
+			return fmt.Errorf("body file can`t be used with data encoding")
+		}
+		if !s.Template {
+			if _, err := base64.StdEncoding.DecodeString(s.Data); err != nil {
+				return fmt.Errorf("data is not valid base64: %s", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown data encoding %q", s.Encoding)
+	}
+
+	return nil
+}
+
+// OpenBody Open stub response body: body file from storage, decoded or plain rendered data.
+// Returns body modification time, zero for data bodies
+func OpenBody(store StubStorage, host *url.URL, stub ServiceStub, data string) (io.ReadSeekCloser, time.Time, error) {
+	if stub.BodyFile != "" {
+		return store.GetBodyFile(host, stub.BodyFile)
+	}
+
+	if stub.Encoding == EncodingBase64 {
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return nopSeekCloser{bytes.NewReader(b)}, time.Time{}, nil
+	}
+
+	return nopSeekCloser{bytes.NewReader([]byte(data))}, time.Time{}, nil
+}
+
+// GetBodyFile Open body file relative to target files dir of FS storage
+func (s FileStubStorage) GetBodyFile(host *url.URL, name string) (io.ReadSeekCloser, time.Time, error) {
+	filename, err := s.bodyFilePath(host, name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, time.Time{}, err
+	}
+
+	return file, stat.ModTime(), nil
+}
+
+// SaveBodyFile Write body file relative to target files dir of FS storage
+func (s FileStubStorage) SaveBodyFile(host *url.URL, name string, data io.Reader) error {
+	filename, err := s.bodyFilePath(host, name)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file: %s", filename)
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(file, data); err != nil {
+		return fmt.Errorf("error writing file: %s", filename)
+	}
+
+	return nil
+}
+
+// GetBodyFile Get body file blob from Redis
+func (s RedisStubStorage) GetBodyFile(host *url.URL, name string) (io.ReadSeekCloser, time.Time, error) {
+	ctx := context.Background()
+	data, err := redisClient.Get(ctx, bodyFilesKey(host, name)).Bytes()
+	if err == redis.Nil {
+		return nil, time.Time{}, fmt.Errorf("body file %s not found", name)
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return nopSeekCloser{bytes.NewReader(data)}, time.Time{}, nil
+}
+
+// SaveBodyFile Save body file blob to Redis
+func (s RedisStubStorage) SaveBodyFile(host *url.URL, name string, data io.Reader) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	return redisClient.Set(ctx, bodyFilesKey(host, name), b, 0).Err()
+}
+
+// GetBodyFile Body files are not cached, they can be large
+func (cs *CachedStorage) GetBodyFile(host *url.URL, name string) (io.ReadSeekCloser, time.Time, error) {
+	return cs.Store.GetBodyFile(host, name)
+}
+
+// SaveBodyFile Save body file to store
+func (cs *CachedStorage) SaveBodyFile(host *url.URL, name string, data io.Reader) error {
+	return cs.Store.SaveBodyFile(host, name, data)
+}
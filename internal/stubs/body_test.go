@@ -0,0 +1,67 @@
+package stubs
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileBodyFiles(t *testing.T) {
+	store := FileStubStorage{FsPath: t.TempDir()}
+	app1, _ := url.Parse("http://127.0.0.1:9090")
+	app2, _ := url.Parse("http://127.0.0.1:9091")
+
+	if err := store.SaveBodyFile(app1, "images/logo.png", strings.NewReader("png")); err != nil {
+		t.Fatalf("SaveBodyFile error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.FsPath, "files", "http_127.0.0.1_9090", "images", "logo.png")); err != nil {
+		t.Errorf("body file not in target files dir: %s", err)
+	}
+
+	file, _, err := store.GetBodyFile(app1, "images/logo.png")
+	if err != nil {
+		t.Fatalf("GetBodyFile error: %s", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "png" {
+		t.Errorf("body file content %q", data)
+	}
+
+	// Files of other target are not shared
+	if _, _, err = store.GetBodyFile(app2, "images/logo.png"); err == nil {
+		t.Error("body file of other target found")
+	}
+
+	for _, name := range []string{"../http_127.0.0.1_9091/x", "../../http_127.0.0.1_9090.yml", "/../../x", ".."} {
+		if err = store.SaveBodyFile(app1, name, strings.NewReader("x")); err == nil {
+			t.Errorf("SaveBodyFile(%q) succeeded, want outside of files dir error", name)
+		}
+		if _, _, err = store.GetBodyFile(app1, name); err == nil {
+			t.Errorf("GetBodyFile(%q) succeeded, want outside of files dir error", name)
+		}
+	}
+}
+
+func TestValidateBodyFile(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"logo.png", true},
+		{"images/logo.png", true},
+		{"../logo.png", false},
+		{"/etc/passwd", false},
+		{"images/../../logo.png", false},
+	}
+
+	for _, tt := range tests {
+		err := ServiceStub{BodyFile: tt.name}.validateBody()
+		if (err == nil) != tt.ok {
+			t.Errorf("validateBody(%q) error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package stubs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RequestPattern Request description: method (ANY for any method), path pattern and conditions
+type RequestPattern struct {
+	Method string
+	Path   string
+	Match  *RequestMatch
+}
+
+// String Condition in readable form
+func (m ValueMatcher) String() string {
+	var parts []string
+	if m.Present != nil && !*m.Present {
+		return "absent"
+	}
+	if m.Equals != "" {
+		parts = append(parts, fmt.Sprintf("equal to %q", m.Equals))
+	}
+	if m.Regex != "" {
+		parts = append(parts, fmt.Sprintf("matching %s", m.Regex))
+	}
+	if len(parts) == 0 {
+		return "present"
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// String Condition in readable form
+func (m BodyMatcher) String() string {
+	var parts []string
+	if m.EqualToJson != nil {
+		parts = append(parts, "equal to JSON")
+	}
+	if m.JsonPath != "" {
+		parts = append(parts, "jsonPath "+m.JsonPath)
+	}
+	if m.XPath != "" {
+		parts = append(parts, "xPath "+m.XPath)
+	}
+	if m.Value != nil {
+		parts = append(parts, "value "+m.Value.String())
+	}
+	if m.Contains != "" {
+		parts = append(parts, fmt.Sprintf("containing %q", m.Contains))
+	}
+	if m.Regex != "" {
+		parts = append(parts, "matching "+m.Regex)
+	}
+	for _, name := range sortedNames(m.Form) {
+		parts = append(parts, fmt.Sprintf("form field %s %s", name, m.Form[name]))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func sortedNames(m map[string]ValueMatcher) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func describeValues(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+
+	return fmt.Sprintf("%q", values)
+}
+
+// Mismatches Conditions request does not satisfy in readable form, empty if request matches
+func (m *RequestMatch) Mismatches(req *RequestData) []string {
+	if m == nil {
+		return nil
+	}
+
+	var reasons []string
+	for _, name := range sortedNames(m.Query) {
+		if vm := m.Query[name]; !vm.Matches(req.Query[name]) {
+			reasons = append(reasons, fmt.Sprintf("query param %s: expected %s, got %s", name, vm, describeValues(req.Query[name])))
+		}
+	}
+	for _, name := range sortedNames(m.Headers) {
+		if vm := m.Headers[name]; !vm.Matches(req.Header.Values(name)) {
+			reasons = append(reasons, fmt.Sprintf("header %s: expected %s, got %s", name, vm, describeValues(req.Header.Values(name))))
+		}
+	}
+	for i, bm := range m.Body {
+		if !bm.Matches(req) {
+			reasons = append(reasons, fmt.Sprintf("body condition %d not satisfied: %s", i+1, bm))
+		}
+	}
+
+	return reasons
+}
+
+// Mismatches Reasons request does not match pattern: method, path and conditions, empty if request matches
+func (p RequestPattern) Mismatches(req *RequestData) []string {
+	var reasons []string
+	if p.Method != "" && p.Method != AnyMethod && !strings.EqualFold(p.Method, req.Method) {
+		reasons = append(reasons, fmt.Sprintf("method: expected %s, got %s", p.Method, req.Method))
+	}
+	if _, ok := MatchPath(p.Path, req.Path); !ok {
+		reasons = append(reasons, fmt.Sprintf("path: expected %s, got %s", p.Path, req.Path))
+	}
+
+	return append(reasons, p.Match.Mismatches(req)...)
+}
+
+// StubNearMiss Stub which did not match request with reasons
+type StubNearMiss struct {
+	Stub       string   `json:"stub"`
+	Mismatches []string `json:"mismatches"`
+}
+
+// NearMisses Stubs closest to request not matched by any stub, at most limit ones. Stubs which path
+// matches come first, then ones with closer path, then ones with fewer mismatches. Stub without mismatches
+// was skipped because its or preferred stub sequence is over
+func (sm *ServiceMap) NearMisses(req *RequestData, states map[string]string, limit int) []StubNearMiss {
+	type candidate struct {
+		miss     StubNearMiss
+		distance int
+	}
+
+	var found []candidate
+	for k, stub := range sm.Service {
+		key := ParseStubKey(k)
+		pattern := RequestPattern{Method: key.Method, Path: key.Path, Match: stub.Match}
+		reasons := pattern.Mismatches(req)
+		if !stub.matchesScenario(states) {
+			reasons = append(reasons, fmt.Sprintf("scenario %s: expected state %s, got %s",
+				stub.Scenario, stub.RequiredState, ScenarioState(states, stub.Scenario)))
+		}
+		if len(reasons) == 0 {
+			if len(stub.Responses) > 0 {
+				reasons = append(reasons, "sequence is over")
+			} else {
+				reasons = append(reasons, "preferred stub sequence is over")
+			}
+		}
+
+		distance := 0
+		if _, ok := MatchPath(key.Path, req.Path); !ok {
+			distance = PathDistance(key.Path, req.Path)
+		}
+		miss := StubNearMiss{Stub: key.String(), Mismatches: reasons}
+		found = append(found, candidate{miss: miss, distance: distance})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if len(a.miss.Mismatches) != len(b.miss.Mismatches) {
+			return len(a.miss.Mismatches) < len(b.miss.Mismatches)
+		}
+		return a.miss.Stub < b.miss.Stub
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	misses := make([]StubNearMiss, len(found))
+	for i, c := range found {
+		misses[i] = c.miss
+	}
+
+	return misses
+}
+
+// PathDistance Edit distance between literal part of path pattern and request path, smaller is closer
+func PathDistance(pattern string, path string) int {
+	literal := strings.TrimPrefix(pattern, RegexPathPrefix)
+	literal = templateVarRe.ReplaceAllString(literal, "")
+	literal = strings.ReplaceAll(literal, "*", "")
+
+	return editDistance(literal, path)
+}
+
+// editDistance Levenshtein distance of strings
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
@@ -0,0 +1,222 @@
+package stubs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/utils"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StubHits Number of responses served by stub and time of the last one
+type StubHits struct {
+	Count   int64     `yaml:"count" json:"count"`
+	LastHit time.Time `yaml:"lastHit" json:"lastHit"`
+}
+
+// Coverage Stubs of host served requests and never hit ones
+type Coverage struct {
+	Stubs    int                 `json:"stubs"`
+	Hit      int                 `json:"hit"`
+	Percent  float64             `json:"percent"`
+	NeverHit []string            `json:"neverHit"`
+	Hits     map[string]StubHits `json:"hits"`
+}
+
+// fileHitsMu Guards hit counter files of FS storage
+var fileHitsMu sync.Mutex
+
+// hitsKey Redis hash with stub hit counters of host
+func hitsKey(host *url.URL) string {
+	return fmt.Sprintf("%s:hits", utils.HostToString(host))
+}
+
+// lastHitsKey Redis hash with stub last hit unix milliseconds of host
+func lastHitsKey(host *url.URL) string {
+	return fmt.Sprintf("%s:lasthits", utils.HostToString(host))
+}
+
+// NewCoverage Coverage of service stubs by hits. Hits of removed stubs are not reported
+func NewCoverage(sm *ServiceMap, hits map[string]StubHits) Coverage {
+	c := Coverage{NeverHit: make([]string, 0), Hits: make(map[string]StubHits)}
+	if sm == nil {
+		return c
+	}
+
+	for k := range sm.Service {
+		key := ParseStubKey(k).String()
+		c.Stubs++
+		if h, ok := hits[key]; ok && h.Count > 0 {
+			c.Hit++
+			c.Hits[key] = h
+		} else {
+			c.NeverHit = append(c.NeverHit, key)
+		}
+	}
+	sort.Strings(c.NeverHit)
+	if c.Stubs > 0 {
+		c.Percent = float64(c.Hit) * 100 / float64(c.Stubs)
+	}
+
+	return c
+}
+
+// hitsFile File with stub hit counters of host next to its stubs file
+func (s FileStubStorage) hitsFile(host *url.URL) string {
+	return filepath.Join(s.FsPath, utils.HostToString(host)+".hits.yml")
+}
+
+// readHits Hit counters of host from file, no counters if file does not exist
+func (s FileStubStorage) readHits(host *url.URL) (map[string]StubHits, error) {
+	hits := make(map[string]StubHits)
+	data, err := os.ReadFile(s.hitsFile(host))
+	if errors.Is(err, fs.ErrNotExist) {
+		return hits, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(data, &hits); err != nil {
+		return nil, fmt.Errorf("error reading hits file %s: %s", s.hitsFile(host), err)
+	}
+	if hits == nil {
+		hits = make(map[string]StubHits)
+	}
+
+	return hits, nil
+}
+
+// writeHits Replace hit counters file of host
+func (s FileStubStorage) writeHits(host *url.URL, hits map[string]StubHits) error {
+	data, err := yaml.Marshal(hits)
+	if err != nil {
+		return err
+	}
+
+	// Counters file is replaced at once, so it is not left half written
+	filename := s.hitsFile(host)
+	if err = os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %s", filename)
+	}
+
+	return os.Rename(filename+".tmp", filename)
+}
+
+// RecordHit Count stub hit in hits file of host, counters survive restart
+func (s FileStubStorage) RecordHit(host *url.URL, key StubKey, at time.Time) error {
+	fileHitsMu.Lock()
+	defer fileHitsMu.Unlock()
+
+	hits, err := s.readHits(host)
+	if err != nil {
+		return err
+	}
+	h := hits[key.String()]
+	hits[key.String()] = StubHits{Count: h.Count + 1, LastHit: at}
+
+	return s.writeHits(host, hits)
+}
+
+// GetHits Hit counters of host stubs by stub key
+func (s FileStubStorage) GetHits(host *url.URL) (map[string]StubHits, error) {
+	fileHitsMu.Lock()
+	defer fileHitsMu.Unlock()
+
+	return s.readHits(host)
+}
+
+// ResetHits Reset stub hit counter, all host counters if key is nil
+func (s FileStubStorage) ResetHits(host *url.URL, key *StubKey) error {
+	fileHitsMu.Lock()
+	defer fileHitsMu.Unlock()
+
+	if key == nil {
+		err := os.Remove(s.hitsFile(host))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	hits, err := s.readHits(host)
+	if err != nil {
+		return err
+	}
+	if _, ok := hits[key.String()]; !ok {
+		return nil
+	}
+	delete(hits, key.String())
+
+	return s.writeHits(host, hits)
+}
+
+// RecordHit Count stub hit in Redis
+func (s RedisStubStorage) RecordHit(host *url.URL, key StubKey, at time.Time) error {
+	ctx := context.Background()
+	pipe := redisClient.TxPipeline()
+	pipe.HIncrBy(ctx, hitsKey(host), key.String(), 1)
+	pipe.HSet(ctx, lastHitsKey(host), key.String(), at.UnixMilli())
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// GetHits Hit counters of host stubs by stub key
+func (s RedisStubStorage) GetHits(host *url.URL) (map[string]StubHits, error) {
+	ctx := context.Background()
+	counts, err := redisClient.HGetAll(ctx, hitsKey(host)).Result()
+	if err != nil {
+		return nil, err
+	}
+	lastHits, err := redisClient.HGetAll(ctx, lastHitsKey(host)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make(map[string]StubHits, len(counts))
+	for k, v := range counts {
+		count, _ := strconv.ParseInt(v, 10, 64)
+		ms, _ := strconv.ParseInt(lastHits[k], 10, 64)
+		hits[k] = StubHits{Count: count, LastHit: time.UnixMilli(ms)}
+	}
+
+	return hits, nil
+}
+
+// ResetHits Reset stub hit counter, all host counters if key is nil
+func (s RedisStubStorage) ResetHits(host *url.URL, key *StubKey) error {
+	ctx := context.Background()
+	if key == nil {
+		return redisClient.Del(ctx, hitsKey(host), lastHitsKey(host)).Err()
+	}
+
+	if err := redisClient.HDel(ctx, hitsKey(host), key.String()).Err(); err != nil {
+		return err
+	}
+
+	return redisClient.HDel(ctx, lastHitsKey(host), key.String()).Err()
+}
+
+// RecordHit Hit counters are not cached, they are changed on every stub response
+func (cs *CachedStorage) RecordHit(host *url.URL, key StubKey, at time.Time) error {
+	return cs.Store.RecordHit(host, key, at)
+}
+
+// GetHits Get hit counters from store
+func (cs *CachedStorage) GetHits(host *url.URL) (map[string]StubHits, error) {
+	return cs.Store.GetHits(host)
+}
+
+// ResetHits Reset hit counters in store
+func (cs *CachedStorage) ResetHits(host *url.URL, key *StubKey) error {
+	return cs.Store.ResetHits(host, key)
+}
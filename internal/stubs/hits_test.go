@@ -0,0 +1,94 @@
+package stubs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewCoverage(t *testing.T) {
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	service := map[string]ServiceStub{
+		"GET /a":       {},
+		"GET /b#v2":    {},
+		"/c":           {},
+		"POST /orders": {},
+	}
+
+	tests := []struct {
+		name     string
+		sm       *ServiceMap
+		hits     map[string]StubHits
+		hit      int
+		percent  float64
+		neverHit []string
+	}{
+		{"no stubs", nil, nil, 0, 0, []string{}},
+		{"no hits", &ServiceMap{Service: service}, nil, 0, 0, []string{"/c", "GET /a", "GET /b#v2", "POST /orders"}},
+		{
+			"some hit",
+			&ServiceMap{Service: service},
+			map[string]StubHits{"GET /a": {Count: 12, LastHit: at}, "POST /orders": {Count: 1, LastHit: at}},
+			2, 50, []string{"/c", "GET /b#v2"},
+		},
+		{
+			"zero and removed stub hits",
+			&ServiceMap{Service: service},
+			map[string]StubHits{"GET /a": {Count: 0}, "GET /removed": {Count: 3}, "GET /b#v2": {Count: 1, LastHit: at}},
+			1, 25, []string{"/c", "GET /a", "POST /orders"},
+		},
+	}
+
+	for _, tt := range tests {
+		c := NewCoverage(tt.sm, tt.hits)
+		if c.Hit != tt.hit || c.Percent != tt.percent || !reflect.DeepEqual(c.NeverHit, tt.neverHit) {
+			t.Errorf("%s: hit %d, percent %v, never hit %v, want %d, %v, %v", tt.name, c.Hit, c.Percent, c.NeverHit, tt.hit, tt.percent, tt.neverHit)
+		}
+		if len(c.Hits) != c.Hit {
+			t.Errorf("%s: hits %v, want only hit stubs", tt.name, c.Hits)
+		}
+	}
+}
+
+func TestFileHits(t *testing.T) {
+	dir := t.TempDir()
+	host, _ := url.Parse("http://127.0.0.1:9090")
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := ParseStubKey("GET /a")
+	b := ParseStubKey("GET /b")
+
+	store := FileStubStorage{FsPath: dir}
+	for _, key := range []StubKey{a, a, b} {
+		if err := store.RecordHit(host, key, at); err != nil {
+			t.Fatalf("RecordHit error: %s", err)
+		}
+	}
+
+	// Counters are read from file by storage created after restart
+	hits, err := FileStubStorage{FsPath: dir}.GetHits(host)
+	if err != nil {
+		t.Fatalf("GetHits error: %s", err)
+	}
+	want := map[string]StubHits{"GET /a": {Count: 2, LastHit: at}, "GET /b": {Count: 1, LastHit: at}}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("hits %v, want %v", hits, want)
+	}
+
+	if err = store.ResetHits(host, &a); err != nil {
+		t.Fatalf("ResetHits error: %s", err)
+	}
+	if hits, _ = store.GetHits(host); !reflect.DeepEqual(hits, map[string]StubHits{"GET /b": {Count: 1, LastHit: at}}) {
+		t.Errorf("hits after stub reset %v", hits)
+	}
+
+	if err = store.ResetHits(host, nil); err != nil {
+		t.Fatalf("ResetHits error: %s", err)
+	}
+	if hits, _ = store.GetHits(host); len(hits) != 0 {
+		t.Errorf("hits after reset %v", hits)
+	}
+	if err = store.ResetHits(host, nil); err != nil {
+		t.Errorf("ResetHits without hits error: %s", err)
+	}
+}
@@ -0,0 +1,389 @@
+package stubs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/jsonpath"
+	"github.com/overdone/stubrouter/internal/xpath"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ValueMatcher Condition on query param or header value.
+// Present set to false requires value to be absent, other conditions require value to be present
+type ValueMatcher struct {
+	Equals  string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Regex   string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Present *bool  `yaml:"present,omitempty" json:"present,omitempty"`
+}
+
+// BodyMatcher Condition on request body. All set fields must be satisfied.
+// JsonPath and XPath must select at least one value, Value condition is checked against selected values
+type BodyMatcher struct {
+	EqualToJson interface{}             `yaml:"equalToJson,omitempty" json:"equalToJson,omitempty"`
+	JsonPath    string                  `yaml:"jsonPath,omitempty" json:"jsonPath,omitempty"`
+	XPath       string                  `yaml:"xPath,omitempty" json:"xPath,omitempty"`
+	Value       *ValueMatcher           `yaml:"value,omitempty" json:"value,omitempty"`
+	Contains    string                  `yaml:"contains,omitempty" json:"contains,omitempty"`
+	Regex       string                  `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Form        map[string]ValueMatcher `yaml:"form,omitempty" json:"form,omitempty"`
+}
+
+// RequestMatch Optional stub conditions. All conditions must be satisfied for stub to match request
+type RequestMatch struct {
+	Query   map[string]ValueMatcher `yaml:"query,omitempty" json:"query,omitempty"`
+	Headers map[string]ValueMatcher `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body    []BodyMatcher           `yaml:"body,omitempty" json:"body,omitempty"`
+}
+
+// RequestData Request params stubs matched against
+type RequestData struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+
+	jsonOnce sync.Once
+	json     interface{}
+	jsonErr  error
+	formOnce sync.Once
+	form     url.Values
+}
+
+var regexCache sync.Map
+
+// NewRequestData Make request data for target path from incoming request.
+// Request body is read and replaced with buffered copy, so request still can be proxied
+func NewRequestData(r *http.Request, path string) (*RequestData, error) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := io.ReadAll(r.Body)
+		_ = r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return &RequestData{
+		Method: r.Method,
+		Path:   path,
+		Query:  r.URL.Query(),
+		Header: r.Header,
+		Body:   body,
+	}, nil
+}
+
+// JSON Request body decoded as JSON
+func (req *RequestData) JSON() (interface{}, error) {
+	req.jsonOnce.Do(func() {
+		req.jsonErr = json.Unmarshal(req.Body, &req.json)
+	})
+
+	return req.json, req.jsonErr
+}
+
+// Form Request body decoded as url encoded form
+func (req *RequestData) Form() url.Values {
+	req.formOnce.Do(func() {
+		ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if ct == "application/x-www-form-urlencoded" || ct == "" {
+			req.form, _ = url.ParseQuery(string(req.Body))
+		}
+		if req.form == nil {
+			req.form = url.Values{}
+		}
+	})
+
+	return req.form
+}
+
+// normalizeJSON Convert value decoded from YAML or JSON to form produced by JSON decoder
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var res interface{}
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+// jsonValueString String representation of JSON value for value matchers. Strings are not quoted
+func jsonValueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// compileRegex Compile regex once, invalid expression never matches
+func compileRegex(expr string) *regexp.Regexp {
+	if re, ok := regexCache.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		re = regexp.MustCompile(`$^`)
+	}
+	regexCache.Store(expr, re)
+
+	return re
+}
+
+// Validate Check matcher regular expression
+func (m ValueMatcher) Validate() error {
+	if m.Regex == "" {
+		return nil
+	}
+
+	_, err := regexp.Compile(m.Regex)
+	return err
+}
+
+// Matches Check matcher against all values of query param or header
+func (m ValueMatcher) Matches(values []string) bool {
+	if m.Present != nil && !*m.Present {
+		return len(values) == 0
+	}
+	if len(values) == 0 {
+		return false
+	}
+
+	for _, v := range values {
+		if (m.Equals == "" || v == m.Equals) && (m.Regex == "" || compileRegex(m.Regex).MatchString(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate Check body condition expressions
+func (m BodyMatcher) Validate() error {
+	if m.EqualToJson != nil {
+		if _, err := normalizeJSON(m.EqualToJson); err != nil {
+			return err
+		}
+	}
+	if m.JsonPath != "" {
+		if _, err := jsonpath.Compile(m.JsonPath); err != nil {
+			return err
+		}
+	}
+	if m.XPath != "" {
+		if err := xpath.Validate(m.XPath); err != nil {
+			return err
+		}
+	}
+	if m.Value != nil && m.JsonPath == "" && m.XPath == "" {
+		return fmt.Errorf("body value condition requires jsonPath or xPath")
+	}
+	if m.Value != nil {
+		if err := m.Value.Validate(); err != nil {
+			return err
+		}
+	}
+	if m.Regex != "" {
+		if _, err := regexp.Compile(m.Regex); err != nil {
+			return err
+		}
+	}
+	for _, vm := range m.Form {
+		if err := vm.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Matches Check request body satisfies condition
+func (m BodyMatcher) Matches(req *RequestData) bool {
+	if m.EqualToJson != nil {
+		expected, err := normalizeJSON(m.EqualToJson)
+		if err != nil {
+			return false
+		}
+		actual, err := req.JSON()
+		if err != nil || !reflect.DeepEqual(expected, actual) {
+			return false
+		}
+	}
+
+	if m.JsonPath != "" {
+		doc, err := req.JSON()
+		if err != nil {
+			return false
+		}
+		found, err := jsonpath.Eval(m.JsonPath, doc)
+		if err != nil || len(found) == 0 {
+			return false
+		}
+		if m.Value != nil {
+			values := make([]string, 0, len(found))
+			for _, v := range found {
+				values = append(values, jsonValueString(v))
+			}
+			if !m.Value.Matches(values) {
+				return false
+			}
+		}
+	}
+
+	if m.XPath != "" {
+		found, err := xpath.Eval(m.XPath, req.Body)
+		if err != nil || len(found) == 0 || (m.Value != nil && !m.Value.Matches(found)) {
+			return false
+		}
+	}
+
+	if m.Contains != "" && !bytes.Contains(req.Body, []byte(m.Contains)) {
+		return false
+	}
+
+	if m.Regex != "" && !compileRegex(m.Regex).Match(req.Body) {
+		return false
+	}
+
+	for name, vm := range m.Form {
+		if !vm.Matches(req.Form()[name]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate Check all match conditions
+func (m *RequestMatch) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	for _, vm := range m.Query {
+		if err := vm.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, vm := range m.Headers {
+		if err := vm.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, bm := range m.Body {
+		if err := bm.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Matches Check request satisfies all conditions. Stub without conditions matches any request
+func (m *RequestMatch) Matches(req *RequestData) bool {
+	if m == nil {
+		return true
+	}
+
+	for name, vm := range m.Query {
+		if !vm.Matches(req.Query[name]) {
+			return false
+		}
+	}
+	for name, vm := range m.Headers {
+		if !vm.Matches(req.Header.Values(name)) {
+			return false
+		}
+	}
+	for _, bm := range m.Body {
+		if !bm.Matches(req) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Weight Number of conditions, stubs with more conditions are more specific
+func (m *RequestMatch) Weight() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.Query) + len(m.Headers) + len(m.Body)
+}
+
+// weight Number of stub conditions including required scenario state
+func (s ServiceStub) weight() int {
+	w := s.Match.Weight()
+	if s.Scenario != "" && s.RequiredState != "" {
+		w++
+	}
+
+	return w
+}
+
+// StubMatch Stub selected for request with path variables captured by stub path pattern
+type StubMatch struct {
+	Key      StubKey
+	Stub     ServiceStub
+	PathVars map[string]string
+}
+
+// Match Find stub for request. Candidates are stubs bound to request method or ANY
+// which path pattern matches request path, conditions are satisfied and required scenario state
+// is current state in passed scenario states. Precedence order:
+// exact path, template, wildcard, regex; then stub bound to method; then stub with more conditions;
+// then pattern with longer literal part; then first by key
+func (sm *ServiceMap) Match(req *RequestData, states map[string]string) (*StubMatch, bool) {
+	var found []*StubMatch
+	for k, stub := range sm.Service {
+		key := ParseStubKey(k)
+		if key.Method != AnyMethod && key.Method != strings.ToUpper(req.Method) {
+			continue
+		}
+
+		vars, ok := MatchPath(key.Path, req.Path)
+		if ok && stub.matchesScenario(states) && stub.Match.Matches(req) {
+			found = append(found, &StubMatch{Key: key, Stub: stub, PathVars: vars})
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, false
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if ak, bk := GetPathKind(a.Key.Path), GetPathKind(b.Key.Path); ak != bk {
+			return ak < bk
+		}
+		if (a.Key.Method == AnyMethod) != (b.Key.Method == AnyMethod) {
+			return b.Key.Method == AnyMethod
+		}
+		if a.Stub.weight() != b.Stub.weight() {
+			return a.Stub.weight() > b.Stub.weight()
+		}
+		if al, bl := pathLiterals(a.Key.Path), pathLiterals(b.Key.Path); al != bl {
+			return al > bl
+		}
+		return a.Key.String() < b.Key.String()
+	})
+
+	return found[0], true
+}
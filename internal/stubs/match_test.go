@@ -0,0 +1,89 @@
+package stubs
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseStubKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want StubKey
+	}{
+		{"GET /users/1", StubKey{Method: "GET", Path: "/users/1"}},
+		{"/users/1", StubKey{Method: AnyMethod, Path: "/users/1"}},
+		{"POST /orders#v2", StubKey{Method: "POST", Path: "/orders", Name: "v2"}},
+		{"/a b", StubKey{Method: AnyMethod, Path: "/a b"}},
+		{"GET regex:^/v[12]/items$", StubKey{Method: "GET", Path: "regex:^/v[12]/items$"}},
+	}
+
+	for _, tt := range tests {
+		got := ParseStubKey(tt.key)
+		if got != tt.want {
+			t.Errorf("ParseStubKey(%q) = %+v, want %+v", tt.key, got, tt.want)
+		}
+		if got.String() != tt.key {
+			t.Errorf("ParseStubKey(%q).String() = %q", tt.key, got.String())
+		}
+	}
+}
+
+func TestMatchPrecedence(t *testing.T) {
+	present := true
+	sm := &ServiceMap{Service: map[string]ServiceStub{
+		"GET /users/1":             {Data: "get exact"},
+		"/users/1":                 {Data: "any exact"},
+		"GET /users/{id}":          {Data: "get template"},
+		"/users/{id}/orders":       {Data: "any template"},
+		"GET /users/*/orders":      {Data: "get wildcard"},
+		"GET /files/**":            {Data: "files wildcard"},
+		"GET /files/img/**":        {Data: "images wildcard"},
+		"GET regex:^/v[12]/items$": {Data: "regex"},
+		"GET /v1/*":                {Data: "v1 wildcard"},
+		"GET /search":              {Data: "search"},
+		"GET /search#filtered": {Data: "filtered", Match: &RequestMatch{
+			Query: map[string]ValueMatcher{"q": {Present: &present}},
+		}},
+		"GET /order#pending": {Data: "pending", Scenario: "order", RequiredState: ScenarioStarted},
+		"GET /order#shipped": {Data: "shipped", Scenario: "order", RequiredState: "shipped"},
+	}}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		states map[string]string
+		want   string
+	}{
+		{"method bound exact", "GET", "/users/1", nil, "get exact"},
+		{"any method fallback", "DELETE", "/users/1", nil, "any exact"},
+		{"exact over template", "get", "/users/1", nil, "get exact"},
+		{"template", "GET", "/users/7", nil, "get template"},
+		{"template over wildcard", "GET", "/users/7/orders", nil, "any template"},
+		{"any method template over method wildcard", "PUT", "/users/7/orders", nil, "any template"},
+		{"longer literal wildcard", "GET", "/files/img/a.png", nil, "images wildcard"},
+		{"shorter literal wildcard", "GET", "/files/doc.txt", nil, "files wildcard"},
+		{"wildcard over regex", "GET", "/v1/items", nil, "v1 wildcard"},
+		{"regex", "GET", "/v2/items", nil, "regex"},
+		{"more conditions", "GET", "/search?q=a", nil, "filtered"},
+		{"conditions not met", "GET", "/search", nil, "search"},
+		{"scenario started", "GET", "/order", nil, "pending"},
+		{"scenario state", "GET", "/order", map[string]string{"order": "shipped"}, "shipped"},
+		{"no stub for method", "POST", "/users/7", nil, ""},
+		{"no stub for path", "GET", "/none", nil, ""},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.target)
+		req := &RequestData{Method: tt.method, Path: u.Path, Query: u.Query(), Header: http.Header{}}
+		match, ok := sm.Match(req, tt.states)
+		got := ""
+		if ok {
+			got = match.Stub.Data
+		}
+		if got != tt.want {
+			t.Errorf("%s: %s %s matched %q, want %q", tt.name, tt.method, tt.target, got, tt.want)
+		}
+	}
+}
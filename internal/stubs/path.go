@@ -0,0 +1,182 @@
+package stubs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// PathKind Kind of stub path pattern. Lower kind takes precedence on match
+type PathKind int
+
+const (
+	PathExact PathKind = iota
+	PathTemplate
+	PathWildcard
+	PathRegex
+)
+
+// RegexPathPrefix Prefix of stub path given as regular expression
+const RegexPathPrefix = "regex:"
+
+// WildcardVar Path variable with path part matched by ** wildcard
+const WildcardVar = "wildcard"
+
+var templateVarRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// pathPattern Stub path compiled to regular expression
+type pathPattern struct {
+	kind     PathKind
+	re       *regexp.Regexp
+	literals int
+}
+
+var pathPatternCache = make(map[string]*pathPattern)
+var pathPatternMu sync.Mutex
+
+// GetPathKind Kind of stub path pattern:
+// exact path, template with {name} segments, wildcard with * and ** segments or regex: prefixed expression
+func GetPathKind(path string) PathKind {
+	switch {
+	case strings.HasPrefix(path, RegexPathPrefix):
+		return PathRegex
+	case strings.Contains(path, "*"):
+		return PathWildcard
+	case templateVarRe.MatchString(path):
+		return PathTemplate
+	default:
+		return PathExact
+	}
+}
+
+// compilePath Convert stub path pattern to regular expression
+func compilePath(path string) (*pathPattern, error) {
+	kind := GetPathKind(path)
+	if kind == PathRegex {
+		re, err := regexp.Compile(strings.TrimPrefix(path, RegexPathPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return &pathPattern{kind: kind, re: re}, nil
+	}
+
+	var expr strings.Builder
+	literals := 0
+	for i, segment := range strings.Split(path, "/") {
+		if i > 0 {
+			expr.WriteString("/")
+		}
+
+		switch segment {
+		case "**":
+			// Any rest of path including nested segments
+			expr.WriteString(fmt.Sprintf("(?P<%s>.*)", WildcardVar))
+			continue
+		case "*":
+			expr.WriteString("[^/]+")
+			continue
+		}
+
+		last := 0
+		for _, loc := range templateVarRe.FindAllStringSubmatchIndex(segment, -1) {
+			literals += writeGlob(&expr, segment[last:loc[0]])
+			expr.WriteString(fmt.Sprintf("(?P<%s>[^/]+)", segment[loc[2]:loc[3]]))
+			last = loc[1]
+		}
+		literals += writeGlob(&expr, segment[last:])
+	}
+
+	re, err := regexp.Compile("^" + expr.String() + "$")
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathPattern{kind: kind, re: re, literals: literals}, nil
+}
+
+// writeGlob Write literal part of segment as expression, * inside segment matches any chars except /.
+// Returns number of literal chars
+func writeGlob(expr *strings.Builder, s string) int {
+	parts := strings.Split(s, "*")
+	for i, part := range parts {
+		if i > 0 {
+			expr.WriteString("[^/]*")
+		}
+		expr.WriteString(regexp.QuoteMeta(part))
+	}
+
+	return len(s) - len(parts) + 1
+}
+
+// FillPathVars Path of template pattern with every {name} variable replaced by value
+func FillPathVars(path string, value string) string {
+	return templateVarRe.ReplaceAllLiteralString(path, value)
+}
+
+// getPathPattern Compile stub path once, invalid pattern never matches
+func getPathPattern(path string) *pathPattern {
+	pathPatternMu.Lock()
+	defer pathPatternMu.Unlock()
+
+	if p, ok := pathPatternCache[path]; ok {
+		return p
+	}
+
+	p, err := compilePath(path)
+	if err != nil {
+		p = nil
+	}
+	pathPatternCache[path] = p
+
+	return p
+}
+
+// ValidatePath Check stub path pattern
+func ValidatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("stub path is empty")
+	}
+
+	_, err := compilePath(path)
+	return err
+}
+
+// pathLiterals Number of literal chars in template or wildcard pattern, longer literal part is more specific
+func pathLiterals(pattern string) int {
+	if p := getPathPattern(pattern); p != nil {
+		return p.literals
+	}
+
+	return 0
+}
+
+// MatchPath Match request path against stub path pattern, returns captured path variables
+func MatchPath(pattern string, path string) (map[string]string, bool) {
+	if GetPathKind(pattern) == PathExact {
+		return nil, pattern == path
+	}
+
+	p := getPathPattern(pattern)
+	if p == nil {
+		return nil, false
+	}
+
+	m := p.re.FindStringSubmatch(path)
+	if m == nil {
+		return nil, false
+	}
+
+	vars := make(map[string]string)
+	for i, name := range p.re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		if name == "" {
+			name = fmt.Sprint(i)
+		}
+		vars[name] = m[i]
+	}
+
+	return vars, true
+}
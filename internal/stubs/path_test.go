@@ -0,0 +1,48 @@
+package stubs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		ok      bool
+		vars    map[string]string
+	}{
+		{"/users/1", "/users/1", true, nil},
+		{"/users/1", "/users/2", false, nil},
+		{"/users/{id}", "/users/7", true, map[string]string{"id": "7"}},
+		{"/users/{id}/orders", "/users/7/x", false, nil},
+		{"/orders/*/items", "/orders/5/items", true, map[string]string{}},
+		{"/orders/*/items", "/orders/5/6/items", false, nil},
+		{"/files/*.png", "/files/x.png", true, map[string]string{}},
+		{"/files/*.png", "/files/.png", true, map[string]string{}},
+		{"/files/*.png", "/files/x.jpg", false, nil},
+		{"/files/*.png", "/files/a/x.png", false, nil},
+		{"/files/img-*-{size}", "/files/img-cat-100", true, map[string]string{"size": "100"}},
+		{"/files/**", "/files/a/b.txt", true, map[string]string{WildcardVar: "a/b.txt"}},
+		{"regex:^/v[0-9]+/(?P<name>\\w+)$", "/v2/users", true, map[string]string{"name": "users"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			vars, ok := MatchPath(tt.pattern, tt.path)
+			if ok != tt.ok {
+				t.Fatalf("MatchPath(%q, %q) ok = %v, want %v", tt.pattern, tt.path, ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(vars, tt.vars) {
+				t.Errorf("MatchPath(%q, %q) vars = %v, want %v", tt.pattern, tt.path, vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestPathLiterals(t *testing.T) {
+	// Separators and wildcards are not literals
+	if got := pathLiterals("/files/*.png"); got != len("files.png") {
+		t.Errorf("pathLiterals = %d, want %d", got, len("files.png"))
+	}
+}
@@ -0,0 +1,164 @@
+package stubs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/jsonpath"
+	"math/big"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData Request data available in templated stub data and headers
+type TemplateData struct {
+	Method   string
+	Path     string
+	PathVars map[string]string
+	Query    map[string]string
+	Headers  map[string]string
+	Cookies  map[string]string
+	Body     interface{}
+	RawBody  string
+}
+
+var templateFuncs = template.FuncMap{
+	"now":          time.Now,
+	"uuid":         newUUID,
+	"randomInt":    randomInt,
+	"base64":       func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"base64Decode": base64Decode,
+	"jsonPath":     jsonPathValue,
+}
+
+// newTemplateData Collect request data for template. Multi-value params and headers are represented by first value
+func newTemplateData(req *RequestData, vars map[string]string) *TemplateData {
+	data := &TemplateData{
+		Method:   req.Method,
+		Path:     req.Path,
+		PathVars: vars,
+		Query:    make(map[string]string),
+		Headers:  make(map[string]string),
+		Cookies:  make(map[string]string),
+		RawBody:  string(req.Body),
+	}
+
+	for k, v := range req.Query {
+		data.Query[k] = v[0]
+	}
+	for k, v := range req.Header {
+		data.Headers[k] = v[0]
+	}
+	for _, c := range (&http.Request{Header: req.Header}).Cookies() {
+		data.Cookies[c.Name] = c.Value
+	}
+	if body, err := req.JSON(); err == nil {
+		data.Body = body
+	}
+
+	return data
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// randomInt Random int in [min, max] range
+func randomInt(min int, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("randomInt: max %d less than min %d", max, min)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
+	if err != nil {
+		return 0, err
+	}
+
+	return min + int(n.Int64()), nil
+}
+
+func base64Decode(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	return string(b), err
+}
+
+// jsonPathValue First value selected from decoded JSON, empty string if nothing selected
+func jsonPathValue(expr string, doc interface{}) (string, error) {
+	found, err := jsonpath.Eval(expr, doc)
+	if err != nil || len(found) == 0 {
+		return "", err
+	}
+
+	return jsonValueString(found[0]), nil
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// validateTemplates Check stub data and header templates syntax
+func (s ServiceStub) validateTemplates() error {
+	if !s.Template {
+		return nil
+	}
+
+	if _, err := parseTemplate("data", s.Data); err != nil {
+		return err
+	}
+	for k, v := range s.Headers {
+		if _, err := parseTemplate(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func executeTemplate(name string, text string, data *TemplateData) (string, error) {
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err = tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+// Render Make stub response data and headers. Template stubs are executed with request data
+// and captured path variables, other stubs respond with data and headers as is
+func (m *StubMatch) Render(req *RequestData) (string, map[string]string, error) {
+	if !m.Stub.Template {
+		return m.Stub.Data, m.Stub.Headers, nil
+	}
+
+	headers := make(map[string]string, len(m.Stub.Headers))
+
+	data := newTemplateData(req, m.PathVars)
+	for k, v := range m.Stub.Headers {
+		value, err := executeTemplate(k, v, data)
+		if err != nil {
+			return "", nil, err
+		}
+		headers[k] = value
+	}
+
+	body, err := executeTemplate("data", m.Stub.Data, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return body, headers, nil
+}
@@ -0,0 +1,45 @@
+package stubs
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	req := &RequestData{Method: "GET", Path: "/users/7", Header: http.Header{"X-Request-Id": {"r1"}}}
+	vars := map[string]string{"id": "7", "1": "7"}
+
+	tests := []struct {
+		name        string
+		stub        ServiceStub
+		wantData    string
+		wantHeaders map[string]string
+	}{
+		{
+			"plain stub is sent as is",
+			ServiceStub{Data: `{"path": "/users/{id}", "group": "{1}"}`, Headers: map[string]string{"Location": "/users/{id}"}},
+			`{"path": "/users/{id}", "group": "{1}"}`,
+			map[string]string{"Location": "/users/{id}"},
+		},
+		{
+			"template stub gets path variables",
+			ServiceStub{Template: true, Data: `{"id": "{{ .PathVars.id }}", "group": "{{ index .PathVars "1" }}"}`,
+				Headers: map[string]string{"Location": "/users/{{ .PathVars.id }}", "X-Request-Id": `{{ index .Headers "X-Request-Id" }}`}},
+			`{"id": "7", "group": "7"}`,
+			map[string]string{"Location": "/users/7", "X-Request-Id": "r1"},
+		},
+	}
+
+	for _, tt := range tests {
+		m := &StubMatch{Stub: tt.stub, PathVars: vars}
+		data, headers, err := m.Render(req)
+		if err != nil {
+			t.Errorf("%s: Render error: %s", tt.name, err)
+			continue
+		}
+		if data != tt.wantData || !reflect.DeepEqual(headers, tt.wantHeaders) {
+			t.Errorf("%s: Render = %q %v, want %q %v", tt.name, data, headers, tt.wantData, tt.wantHeaders)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package stubs
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"github.com/overdone/stubrouter/internal/utils"
+	"net/url"
+	"sync"
+)
+
+// ScenarioStarted Initial state of every scenario
+const ScenarioStarted = "Started"
+
+// fileScenarios Scenario states of FS storage by host. States are kept in memory and reset on restart
+var fileScenarios = make(map[string]map[string]string)
+var fileScenariosMu sync.Mutex
+
+// moveScenarioScript Compare and set of scenario state: KEYS[1] states hash, ARGV scenario, from, to and started state
+var moveScenarioScript = redis.NewScript(`
+local current = redis.call("HGET", KEYS[1], ARGV[1])
+if not current or current == "" then
+	current = ARGV[4]
+end
+if ARGV[2] ~= "" and current ~= ARGV[2] then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+return 1
+`)
+
+// scenariosKey Redis hash with scenario states of host
+func scenariosKey(host *url.URL) string {
+	return fmt.Sprintf("%s:scenarios", utils.HostToString(host))
+}
+
+// HasScenarios Check any stub takes part in scenario
+func (sm *ServiceMap) HasScenarios() bool {
+	for _, stub := range sm.Service {
+		if stub.Scenario != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scenarios Names of all scenarios used by stubs
+func (sm *ServiceMap) Scenarios() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, stub := range sm.Service {
+		if stub.Scenario != "" && !seen[stub.Scenario] {
+			seen[stub.Scenario] = true
+			names = append(names, stub.Scenario)
+		}
+	}
+
+	return names
+}
+
+// ScenarioState Current scenario state, scenario not stored yet is in started state
+func ScenarioState(states map[string]string, scenario string) string {
+	if state, ok := states[scenario]; ok && state != "" {
+		return state
+	}
+
+	return ScenarioStarted
+}
+
+// matchesScenario Check stub required state is scenario current state
+func (s ServiceStub) matchesScenario(states map[string]string) bool {
+	return s.Scenario == "" || s.RequiredState == "" || ScenarioState(states, s.Scenario) == s.RequiredState
+}
+
+// validateScenario Check scenario params consistency
+func (s ServiceStub) validateScenario() error {
+	if s.Scenario == "" && (s.RequiredState != "" || s.NewState != "") {
+		return fmt.Errorf("scenario states set without scenario name")
+	}
+
+	return nil
+}
+
+// GetScenarioStates Get scenario states of host from memory
+func (s FileStubStorage) GetScenarioStates(host *url.URL) (map[string]string, error) {
+	fileScenariosMu.Lock()
+	defer fileScenariosMu.Unlock()
+
+	states := make(map[string]string)
+	for k, v := range fileScenarios[utils.HostToString(host)] {
+		states[k] = v
+	}
+
+	return states, nil
+}
+
+// SetScenarioState Save scenario state to memory
+func (s FileStubStorage) SetScenarioState(host *url.URL, scenario string, state string) error {
+	fileScenariosMu.Lock()
+	defer fileScenariosMu.Unlock()
+
+	key := utils.HostToString(host)
+	if fileScenarios[key] == nil {
+		fileScenarios[key] = make(map[string]string)
+	}
+	fileScenarios[key][scenario] = state
+
+	return nil
+}
+
+// MoveScenario Set scenario state in memory if it is still in from state, any state if from is empty
+func (s FileStubStorage) MoveScenario(host *url.URL, scenario string, from string, to string) (bool, error) {
+	fileScenariosMu.Lock()
+	defer fileScenariosMu.Unlock()
+
+	key := utils.HostToString(host)
+	if from != "" && ScenarioState(fileScenarios[key], scenario) != from {
+		return false, nil
+	}
+	if fileScenarios[key] == nil {
+		fileScenarios[key] = make(map[string]string)
+	}
+	fileScenarios[key][scenario] = to
+
+	return true, nil
+}
+
+// ResetScenarios Return all host scenarios to started state
+func (s FileStubStorage) ResetScenarios(host *url.URL) error {
+	fileScenariosMu.Lock()
+	defer fileScenariosMu.Unlock()
+
+	delete(fileScenarios, utils.HostToString(host))
+	return nil
+}
+
+// GetScenarioStates Get scenario states of host from Redis
+func (s RedisStubStorage) GetScenarioStates(host *url.URL) (map[string]string, error) {
+	ctx := context.Background()
+	return redisClient.HGetAll(ctx, scenariosKey(host)).Result()
+}
+
+// SetScenarioState Save scenario state to Redis
+func (s RedisStubStorage) SetScenarioState(host *url.URL, scenario string, state string) error {
+	ctx := context.Background()
+	return redisClient.HSet(ctx, scenariosKey(host), scenario, state).Err()
+}
+
+// MoveScenario Set scenario state in Redis if it is still in from state, any state if from is empty.
+// State is checked and set by script at once, so instances sharing Redis can`t both move scenario
+func (s RedisStubStorage) MoveScenario(host *url.URL, scenario string, from string, to string) (bool, error) {
+	ctx := context.Background()
+	moved, err := moveScenarioScript.Run(ctx, redisClient, []string{scenariosKey(host)}, scenario, from, to, ScenarioStarted).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return moved == 1, nil
+}
+
+// ResetScenarios Return all host scenarios to started state
+func (s RedisStubStorage) ResetScenarios(host *url.URL) error {
+	ctx := context.Background()
+	return redisClient.Del(ctx, scenariosKey(host)).Err()
+}
+
+// GetScenarioStates Scenario states are not cached, they are changed on every scenario step
+func (cs *CachedStorage) GetScenarioStates(host *url.URL) (map[string]string, error) {
+	return cs.Store.GetScenarioStates(host)
+}
+
+// SetScenarioState Save scenario state to store
+func (cs *CachedStorage) SetScenarioState(host *url.URL, scenario string, state string) error {
+	return cs.Store.SetScenarioState(host, scenario, state)
+}
+
+// MoveScenario Move scenario state in store
+func (cs *CachedStorage) MoveScenario(host *url.URL, scenario string, from string, to string) (bool, error) {
+	return cs.Store.MoveScenario(host, scenario, from, to)
+}
+
+// ResetScenarios Reset scenario states in store
+func (cs *CachedStorage) ResetScenarios(host *url.URL) error {
+	return cs.Store.ResetScenarios(host)
+}
@@ -0,0 +1,145 @@
+package stubs
+
+import (
+	"context"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/utils"
+	"net/url"
+	"sync"
+)
+
+const (
+	// SequenceRepeatLast Repeat last response when sequence is over
+	SequenceRepeatLast = "repeat-last"
+	// SequenceCycle Start sequence over again
+	SequenceCycle = "cycle"
+	// SequenceFallthrough Proxy request to target when sequence is over
+	SequenceFallthrough = "fallthrough"
+)
+
+// StubResponse Single response of stub response sequence. Stub headers and timeout are used if not set
+type StubResponse struct {
+	Code    int               `yaml:"code" json:"code"`
+	Data    string            `yaml:"data" json:"data"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Timeout int               `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// fileSequences Sequence counters of FS storage by host and stub key
+var fileSequences = make(map[string]map[string]int64)
+var fileSequencesMu sync.Mutex
+
+// sequencesKey Redis hash with sequence counters of host
+func sequencesKey(host *url.URL) string {
+	return fmt.Sprintf("%s:sequences", utils.HostToString(host))
+}
+
+// validateSequence Check sequence params
+func (s ServiceStub) validateSequence() error {
+	switch s.SequenceMode {
+	case "", SequenceRepeatLast, SequenceCycle, SequenceFallthrough:
+	default:
+		return fmt.Errorf("unknown sequence mode %q", s.SequenceMode)
+	}
+
+	for i, resp := range s.Responses {
+		if resp.Code == 0 {
+			return fmt.Errorf("response %d code is not set", i)
+		}
+	}
+
+	return nil
+}
+
+// SequenceResponse Stub with response of call number n (starting from 0) from response sequence.
+// Returns false if sequence is over in fallthrough mode. Stub without sequence is returned as is
+func (s ServiceStub) SequenceResponse(n int64) (ServiceStub, bool) {
+	count := int64(len(s.Responses))
+	if count == 0 {
+		return s, true
+	}
+
+	idx := n
+	if n >= count {
+		switch s.SequenceMode {
+		case SequenceCycle:
+			idx = n % count
+		case SequenceFallthrough:
+			return s, false
+		default:
+			idx = count - 1
+		}
+	}
+
+	resp := s.Responses[idx]
+	s.Code, s.Data = resp.Code, resp.Data
+	if resp.Headers != nil {
+		s.Headers = resp.Headers
+	}
+	if resp.Timeout != 0 {
+		s.Timeout = resp.Timeout
+	}
+
+	return s, true
+}
+
+// NextSequenceIndex Increment stub sequence counter in memory, returns calls number before increment
+func (s FileStubStorage) NextSequenceIndex(host *url.URL, key StubKey) (int64, error) {
+	fileSequencesMu.Lock()
+	defer fileSequencesMu.Unlock()
+
+	hostKey := utils.HostToString(host)
+	if fileSequences[hostKey] == nil {
+		fileSequences[hostKey] = make(map[string]int64)
+	}
+	n := fileSequences[hostKey][key.String()]
+	fileSequences[hostKey][key.String()] = n + 1
+
+	return n, nil
+}
+
+// ResetSequences Reset stub sequence counter, all host counters if key is nil
+func (s FileStubStorage) ResetSequences(host *url.URL, key *StubKey) error {
+	fileSequencesMu.Lock()
+	defer fileSequencesMu.Unlock()
+
+	hostKey := utils.HostToString(host)
+	if key == nil {
+		delete(fileSequences, hostKey)
+	} else if fileSequences[hostKey] != nil {
+		delete(fileSequences[hostKey], key.String())
+	}
+
+	return nil
+}
+
+// NextSequenceIndex Increment stub sequence counter in Redis, returns calls number before increment
+func (s RedisStubStorage) NextSequenceIndex(host *url.URL, key StubKey) (int64, error) {
+	ctx := context.Background()
+	n, err := redisClient.HIncrBy(ctx, sequencesKey(host), key.String(), 1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return n - 1, nil
+}
+
+// ResetSequences Reset stub sequence counter, all host counters if key is nil
+func (s RedisStubStorage) ResetSequences(host *url.URL, key *StubKey) error {
+	ctx := context.Background()
+	if key == nil {
+		return redisClient.Del(ctx, sequencesKey(host)).Err()
+	}
+
+	return redisClient.HDel(ctx, sequencesKey(host), key.String()).Err()
+}
+
+// NextSequenceIndex Sequence counters are not cached, they are changed on every call
+func (cs *CachedStorage) NextSequenceIndex(host *url.URL, key StubKey) (int64, error) {
+	return cs.Store.NextSequenceIndex(host, key)
+}
+
+// ResetSequences Reset sequence counters in store
+func (cs *CachedStorage) ResetSequences(host *url.URL, key *StubKey) error {
+	return cs.Store.ResetSequences(host, key)
+}
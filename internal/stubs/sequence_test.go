@@ -0,0 +1,124 @@
+package stubs
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSequenceResponse(t *testing.T) {
+	responses := []StubResponse{
+		{Code: 503, Data: "down"},
+		{Code: 503, Data: "down", Timeout: 50},
+		{Code: 200, Data: "up", Headers: map[string]string{"X-State": "up"}},
+	}
+
+	tests := []struct {
+		mode  string
+		codes []int
+		ok    []bool
+	}{
+		{"", []int{503, 503, 200, 200, 200}, []bool{true, true, true, true, true}},
+		{SequenceRepeatLast, []int{503, 503, 200, 200, 200}, []bool{true, true, true, true, true}},
+		{SequenceCycle, []int{503, 503, 200, 503, 503}, []bool{true, true, true, true, true}},
+		{SequenceFallthrough, []int{503, 503, 200, 0, 0}, []bool{true, true, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		stub := ServiceStub{Code: 418, Headers: map[string]string{"X-Stub": "1"}, Timeout: 10, Responses: responses, SequenceMode: tt.mode}
+		for n := range tt.codes {
+			got, ok := stub.SequenceResponse(int64(n))
+			if ok != tt.ok[n] || ok && got.Code != tt.codes[n] {
+				t.Errorf("mode %q call %d: code %d, ok %t, want %d, %t", tt.mode, n, got.Code, ok, tt.codes[n], tt.ok[n])
+			}
+		}
+	}
+
+	// Response headers and timeout replace stub ones only when set
+	stub := ServiceStub{Headers: map[string]string{"X-Stub": "1"}, Timeout: 10, Responses: responses}
+	first, _ := stub.SequenceResponse(0)
+	second, _ := stub.SequenceResponse(1)
+	last, _ := stub.SequenceResponse(2)
+	if first.Timeout != 10 || second.Timeout != 50 || !reflect.DeepEqual(first.Headers, stub.Headers) ||
+		!reflect.DeepEqual(last.Headers, map[string]string{"X-State": "up"}) || last.Data != "up" {
+		t.Errorf("sequence responses %+v %+v %+v", first, second, last)
+	}
+
+	plain := ServiceStub{Code: 200, Data: "plain"}
+	if got, ok := plain.SequenceResponse(5); !ok || !reflect.DeepEqual(got, plain) {
+		t.Errorf("stub without sequence %+v, want as is", got)
+	}
+}
+
+func TestValidateSequence(t *testing.T) {
+	tests := []struct {
+		name string
+		stub ServiceStub
+		ok   bool
+	}{
+		{"no sequence", ServiceStub{}, true},
+		{"cycle", ServiceStub{SequenceMode: SequenceCycle, Responses: []StubResponse{{Code: 200}}}, true},
+		{"unknown mode", ServiceStub{SequenceMode: "loop"}, false},
+		{"response without code", ServiceStub{Responses: []StubResponse{{Data: "x"}}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.stub.validateSequence(); (err == nil) != tt.ok {
+			t.Errorf("%s: validateSequence error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestFileSequenceCounters(t *testing.T) {
+	store := FileStubStorage{}
+	host, _ := url.Parse("http://sequence.test:8080")
+	a, b := ParseStubKey("GET /a"), ParseStubKey("GET /b")
+	defer store.ResetSequences(host, nil)
+
+	// Concurrent calls get distinct call numbers
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var got []int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := store.NextSequenceIndex(host, a)
+			if err != nil {
+				t.Errorf("NextSequenceIndex error: %s", err)
+			}
+			mu.Lock()
+			got = append(got, int(n))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Ints(got)
+	for i, n := range got {
+		if n != i {
+			t.Fatalf("call numbers %v, want 0..19", got)
+		}
+	}
+
+	if n, _ := store.NextSequenceIndex(host, b); n != 0 {
+		t.Errorf("other stub call number %d, want 0", n)
+	}
+	if err := store.ResetSequences(host, &a); err != nil {
+		t.Fatalf("ResetSequences error: %s", err)
+	}
+	if n, _ := store.NextSequenceIndex(host, a); n != 0 {
+		t.Errorf("call number after stub reset %d, want 0", n)
+	}
+	if n, _ := store.NextSequenceIndex(host, b); n != 1 {
+		t.Errorf("other stub call number after stub reset %d, want 1", n)
+	}
+
+	if err := store.ResetSequences(host, nil); err != nil {
+		t.Fatalf("ResetSequences error: %s", err)
+	}
+	if n, _ := store.NextSequenceIndex(host, b); n != 0 {
+		t.Errorf("call number after reset %d, want 0", n)
+	}
+}
@@ -0,0 +1,51 @@
+package stubs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SSEEvent Server-Sent Event sent after Delay ms
+type SSEEvent struct {
+	Event string `yaml:"event,omitempty" json:"event,omitempty"`
+	Data  string `yaml:"data" json:"data"`
+	ID    string `yaml:"id,omitempty" json:"id,omitempty"`
+	Delay int    `yaml:"delay,omitempty" json:"delay,omitempty"`
+}
+
+// EventStream Script of Server-Sent Events stub. Script is played Repeat more times after first run,
+// forever if Repeat is -1
+type EventStream struct {
+	Events []SSEEvent `yaml:"events" json:"events"`
+	Repeat int        `yaml:"repeat,omitempty" json:"repeat,omitempty"`
+}
+
+// Validate Check event stream script
+func (es *EventStream) Validate() error {
+	if es == nil {
+		return nil
+	}
+
+	if len(es.Events) == 0 {
+		return fmt.Errorf("event stream has no events")
+	}
+	if es.Repeat < -1 {
+		return fmt.Errorf("event stream repeat must be -1 or more")
+	}
+	totalDelay := 0
+	for i, e := range es.Events {
+		if e.Delay < 0 {
+			return fmt.Errorf("event %d delay must not be negative", i)
+		}
+		// Line breaks would start new event fields
+		if strings.ContainsAny(e.Event, "\r\n") || strings.ContainsAny(e.ID, "\r\n") {
+			return fmt.Errorf("event %d name and id must not contain line breaks", i)
+		}
+		totalDelay += e.Delay
+	}
+	if es.Repeat == -1 && totalDelay == 0 {
+		return fmt.Errorf("event stream repeated forever must have delay")
+	}
+
+	return nil
+}
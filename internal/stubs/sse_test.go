@@ -0,0 +1,29 @@
+package stubs
+
+import "testing"
+
+func TestEventStreamValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream *EventStream
+		ok     bool
+	}{
+		{"no script", nil, true},
+		{"single run", &EventStream{Events: []SSEEvent{{Data: "a"}}}, true},
+		{"repeated", &EventStream{Events: []SSEEvent{{Data: "a"}}, Repeat: 3}, true},
+		{"forever with delay", &EventStream{Events: []SSEEvent{{Data: "a"}, {Data: "b", Delay: 100}}, Repeat: -1}, true},
+		{"forever without delay", &EventStream{Events: []SSEEvent{{Data: "a"}, {Data: "b"}}, Repeat: -1}, false},
+		{"no events", &EventStream{}, false},
+		{"bad repeat", &EventStream{Events: []SSEEvent{{Data: "a"}}, Repeat: -2}, false},
+		{"negative delay", &EventStream{Events: []SSEEvent{{Data: "a", Delay: -1}}}, false},
+		{"event with line break", &EventStream{Events: []SSEEvent{{Event: "a\ndata: x", Data: "a"}}}, false},
+		{"id with carriage return", &EventStream{Events: []SSEEvent{{ID: "1\revent: x", Data: "a"}}}, false},
+		{"multiline data", &EventStream{Events: []SSEEvent{{Data: "a\nb"}}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.stream.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
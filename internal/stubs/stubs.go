@@ -6,32 +6,95 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v9"
 	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/latency"
 	"github.com/overdone/stubrouter/internal/utils"
 	"github.com/patrickmn/go-cache"
 	"gopkg.in/yaml.v3"
+	"io"
 	"log"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
+// AnyMethod Stub key method matching requests with any HTTP method
+const AnyMethod = "ANY"
+
+// Stub faults breaking response on connection level
+const (
+	// FaultConnectionReset Reset connection without response
+	FaultConnectionReset = "connection-reset"
+	// FaultEmptyResponse Close connection without response
+	FaultEmptyResponse = "empty-response"
+	// FaultTruncatedBody Send half of body with Content-Length of full body and close connection
+	FaultTruncatedBody = "truncated-body"
+	// FaultGarbage Send random bytes instead of HTTP response and close connection
+	FaultGarbage = "garbage"
+	// FaultSlowDrip Send body byte by byte with DripDelay ms between bytes (100 ms by default)
+	FaultSlowDrip = "slow-drip"
+)
+
 type ServiceStub struct {
-	Code    int               `yaml:"code" json:"code"`
-	Data    string            `yaml:"data" json:"data"`
-	Headers map[string]string `yaml:"headers" json:"headers"`
-	Timeout int               `yaml:"timeout" json:"timeout"`
+	Code     int               `yaml:"code" json:"code"`
+	Data     string            `yaml:"data" json:"data"`
+	Headers  map[string]string `yaml:"headers" json:"headers"`
+	Timeout  int               `yaml:"timeout" json:"timeout"`
+	Match    *RequestMatch     `yaml:"match,omitempty" json:"match,omitempty"`
+	Template bool              `yaml:"template,omitempty" json:"template,omitempty"`
+
+	Scenario      string `yaml:"scenario,omitempty" json:"scenario,omitempty"`
+	RequiredState string `yaml:"requiredState,omitempty" json:"requiredState,omitempty"`
+	NewState      string `yaml:"newState,omitempty" json:"newState,omitempty"`
+
+	Responses    []StubResponse `yaml:"responses,omitempty" json:"responses,omitempty"`
+	SequenceMode string         `yaml:"sequenceMode,omitempty" json:"sequenceMode,omitempty"`
+
+	Fault     string `yaml:"fault,omitempty" json:"fault,omitempty"`
+	DripDelay int    `yaml:"dripDelay,omitempty" json:"dripDelay,omitempty"`
+
+	Latency      *latency.Profile `yaml:"latency,omitempty" json:"latency,omitempty"`
+	ChunkSize    int              `yaml:"chunkSize,omitempty" json:"chunkSize,omitempty"`
+	ChunkLatency *latency.Profile `yaml:"chunkLatency,omitempty" json:"chunkLatency,omitempty"`
+	Bandwidth    int              `yaml:"bandwidth,omitempty" json:"bandwidth,omitempty"`
+
+	Encoding string `yaml:"encoding,omitempty" json:"encoding,omitempty"`
+	BodyFile string `yaml:"bodyFile,omitempty" json:"bodyFile,omitempty"`
+
+	SSE       *EventStream     `yaml:"sse,omitempty" json:"sse,omitempty"`
+	WebSocket *WebSocketScript `yaml:"websocket,omitempty" json:"websocket,omitempty"`
 }
 
 type ServiceMap struct {
 	Service map[string]ServiceStub
 }
 
+// StubKey Identifies stub in service map by request method, path and variant name.
+// Variants allow several stubs with different match conditions for same method and path
+type StubKey struct {
+	Method string
+	Path   string
+	Name   string
+}
+
 type StubStorage interface {
 	InitStorage(cfg *config.StubRouterConfig) error
 	GetServiceStubs(host *url.URL) (*ServiceMap, error)
-	SaveServiceStub(host *url.URL, path string, data ServiceStub) error
-	RemoveServiceStub(host *url.URL, path string) error
+	SaveServiceStub(host *url.URL, key StubKey, data ServiceStub) error
+	RemoveServiceStub(host *url.URL, key StubKey) error
+	GetScenarioStates(host *url.URL) (map[string]string, error)
+	SetScenarioState(host *url.URL, scenario string, state string) error
+	MoveScenario(host *url.URL, scenario string, from string, to string) (bool, error)
+	ResetScenarios(host *url.URL) error
+	NextSequenceIndex(host *url.URL, key StubKey) (int64, error)
+	ResetSequences(host *url.URL, key *StubKey) error
+	RecordHit(host *url.URL, key StubKey, at time.Time) error
+	GetHits(host *url.URL) (map[string]StubHits, error)
+	ResetHits(host *url.URL, key *StubKey) error
+	GetBodyFile(host *url.URL, name string) (io.ReadSeekCloser, time.Time, error)
+	SaveBodyFile(host *url.URL, name string, data io.Reader) error
 }
 
 type FileStubStorage struct {
@@ -49,6 +112,110 @@ type CachedStorage struct {
 
 var redisClient *redis.Client
 
+// fileMu Serializes stub file rewrites, recorded stubs can be saved concurrently
+var fileMu sync.Mutex
+
+// NewStubKey Make stub key, empty method means any method
+func NewStubKey(method string, path string, name string) StubKey {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = AnyMethod
+	}
+
+	return StubKey{Method: method, Path: path, Name: strings.TrimSpace(name)}
+}
+
+// ParseStubKey Parse service map key in "METHOD path#name" format.
+// Keys without method prefix match any method, name suffix is optional
+func ParseStubKey(key string) StubKey {
+	name := ""
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		key, name = key[:i], key[i+1:]
+	}
+
+	if i := strings.Index(key, " "); i > 0 {
+		method := key[:i]
+		if strings.ToUpper(method) == method && !strings.ContainsAny(method, "/:") {
+			return NewStubKey(method, key[i+1:], name)
+		}
+	}
+
+	return NewStubKey(AnyMethod, key, name)
+}
+
+// String Service map key of stub. Stubs for any method stored by plain path
+func (k StubKey) String() string {
+	key := k.Path
+	if k.Method != "" && k.Method != AnyMethod {
+		key = fmt.Sprintf("%s %s", k.Method, k.Path)
+	}
+	if k.Name != "" {
+		key = fmt.Sprintf("%s#%s", key, k.Name)
+	}
+
+	return key
+}
+
+// Validate Check stub params which can not be checked on decode
+func (s ServiceStub) Validate() error {
+	if err := s.Match.Validate(); err != nil {
+		return err
+	}
+	if err := s.validateScenario(); err != nil {
+		return err
+	}
+	if err := s.validateSequence(); err != nil {
+		return err
+	}
+	if err := s.validateBody(); err != nil {
+		return err
+	}
+	if err := s.SSE.Validate(); err != nil {
+		return err
+	}
+	if err := s.WebSocket.Validate(); err != nil {
+		return err
+	}
+	if s.SSE != nil && s.WebSocket != nil {
+		return fmt.Errorf("stub can`t be both event stream and websocket")
+	}
+
+	switch s.Fault {
+	case "", FaultConnectionReset, FaultEmptyResponse, FaultTruncatedBody, FaultGarbage, FaultSlowDrip:
+	default:
+		return fmt.Errorf("unknown fault %q", s.Fault)
+	}
+
+	if s.ChunkSize < 0 {
+		return fmt.Errorf("chunk size must not be negative")
+	}
+	if s.Bandwidth < 0 {
+		return fmt.Errorf("bandwidth must not be negative")
+	}
+	if err := s.Latency.Validate(); err != nil {
+		return err
+	}
+	if err := s.ChunkLatency.Validate(); err != nil {
+		return err
+	}
+
+	return s.validateTemplates()
+}
+
+// FirstByteLatency Delay before response is started: sampled from latency profile if set, fixed timeout otherwise
+func (s ServiceStub) FirstByteLatency() time.Duration {
+	if s.Latency != nil {
+		return s.Latency.Sample()
+	}
+
+	return time.Duration(s.Timeout) * time.Millisecond
+}
+
+// Validate Check stub key path pattern
+func (k StubKey) Validate() error {
+	return ValidatePath(k.Path)
+}
+
 // InitStorage Inits FS storage
 func (s FileStubStorage) InitStorage(cfg *config.StubRouterConfig) error {
 	return nil
@@ -74,7 +241,10 @@ func (s FileStubStorage) GetServiceStubs(host *url.URL) (*ServiceMap, error) {
 }
 
 // SaveServiceStub Save stub data to FS
-func (s FileStubStorage) SaveServiceStub(host *url.URL, path string, data ServiceStub) error {
+func (s FileStubStorage) SaveServiceStub(host *url.URL, key StubKey, data ServiceStub) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
 	filename := fmt.Sprintf("%s/%s.yml", s.FsPath, utils.HostToString(host))
 	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
@@ -96,7 +266,7 @@ func (s FileStubStorage) SaveServiceStub(host *url.URL, path string, data Servic
 		return err
 	}
 
-	servMap.Service[path] = data
+	servMap.Service[key.String()] = data
 	enc := yaml.NewEncoder(file)
 	err = enc.Encode(servMap)
 	if err != nil {
@@ -106,7 +276,10 @@ func (s FileStubStorage) SaveServiceStub(host *url.URL, path string, data Servic
 	return nil
 }
 
-func (s FileStubStorage) RemoveServiceStub(host *url.URL, path string) error {
+func (s FileStubStorage) RemoveServiceStub(host *url.URL, key StubKey) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
 	filename := fmt.Sprintf("%s/%s.yml", s.FsPath, utils.HostToString(host))
 	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
@@ -128,7 +301,7 @@ func (s FileStubStorage) RemoveServiceStub(host *url.URL, path string) error {
 		return err
 	}
 
-	delete(servMap.Service, path)
+	delete(servMap.Service, key.String())
 	enc := yaml.NewEncoder(file)
 	err = enc.Encode(servMap)
 	if err != nil {
@@ -174,14 +347,14 @@ func (s RedisStubStorage) GetServiceStubs(host *url.URL) (*ServiceMap, error) {
 }
 
 // SaveServiceStub Save stub data to Redis
-func (s RedisStubStorage) SaveServiceStub(host *url.URL, path string, data ServiceStub) error {
+func (s RedisStubStorage) SaveServiceStub(host *url.URL, key StubKey, data ServiceStub) error {
 	val, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
-	err = redisClient.HSet(ctx, utils.HostToString(host), path, val).Err()
+	err = redisClient.HSet(ctx, utils.HostToString(host), key.String(), val).Err()
 	if err != nil {
 		return nil
 	}
@@ -190,9 +363,9 @@ func (s RedisStubStorage) SaveServiceStub(host *url.URL, path string, data Servi
 }
 
 // RemoveServiceStub Remove service stub from Redis
-func (s RedisStubStorage) RemoveServiceStub(host *url.URL, path string) error {
+func (s RedisStubStorage) RemoveServiceStub(host *url.URL, key StubKey) error {
 	ctx := context.Background()
-	err := redisClient.HDel(ctx, utils.HostToString(host), path).Err()
+	err := redisClient.HDel(ctx, utils.HostToString(host), key.String()).Err()
 	if err != nil {
 		return err
 	}
@@ -232,15 +405,13 @@ func (cs *CachedStorage) GetServiceStubs(host *url.URL) (*ServiceMap, error) {
 }
 
 // SaveServiceStub Save stub data to store
-func (cs *CachedStorage) SaveServiceStub(host *url.URL, path string, data ServiceStub) error {
-	key := utils.HostToString(host)
-	cs.Cache.Delete(key)
-	return cs.Store.SaveServiceStub(host, path, data)
+func (cs *CachedStorage) SaveServiceStub(host *url.URL, key StubKey, data ServiceStub) error {
+	cs.Cache.Delete(utils.HostToString(host))
+	return cs.Store.SaveServiceStub(host, key, data)
 }
 
 // RemoveServiceStub Remove service stub from cached store
-func (cs *CachedStorage) RemoveServiceStub(host *url.URL, path string) error {
-	key := utils.HostToString(host)
-	cs.Cache.Delete(key)
-	return cs.Store.RemoveServiceStub(host, path)
+func (cs *CachedStorage) RemoveServiceStub(host *url.URL, key StubKey) error {
+	cs.Cache.Delete(utils.HostToString(host))
+	return cs.Store.RemoveServiceStub(host, key)
 }
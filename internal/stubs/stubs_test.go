@@ -0,0 +1,24 @@
+package stubs
+
+import "testing"
+
+func TestValidateFault(t *testing.T) {
+	tests := []struct {
+		fault string
+		ok    bool
+	}{
+		{"", true},
+		{FaultConnectionReset, true},
+		{FaultEmptyResponse, true},
+		{FaultTruncatedBody, true},
+		{FaultGarbage, true},
+		{FaultSlowDrip, true},
+		{"timeout", false},
+	}
+
+	for _, tt := range tests {
+		if err := (ServiceStub{Code: 200, Fault: tt.fault}).Validate(); (err == nil) != tt.ok {
+			t.Errorf("fault %q: Validate error %v, want ok %t", tt.fault, err, tt.ok)
+		}
+	}
+}
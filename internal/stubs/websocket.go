@@ -0,0 +1,110 @@
+package stubs
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// WebSocket message types
+const (
+	WSText   = "text"
+	WSBinary = "binary"
+)
+
+// WSMessage Message sent by WebSocket stub after Delay ms. Binary message data is base64 encoded
+type WSMessage struct {
+	Type  string `yaml:"type,omitempty" json:"type,omitempty"`
+	Data  string `yaml:"data" json:"data"`
+	Delay int    `yaml:"delay,omitempty" json:"delay,omitempty"`
+}
+
+// WSReply Messages sent in reply to incoming message matching all conditions. Empty conditions match any message
+type WSReply struct {
+	Match    []BodyMatcher `yaml:"match,omitempty" json:"match,omitempty"`
+	Messages []WSMessage   `yaml:"messages" json:"messages"`
+}
+
+// WebSocketScript Script of WebSocket stub. Connection is closed with CloseCode after CloseAfter ms
+// since connect, kept open until client closes it if CloseAfter is 0
+type WebSocketScript struct {
+	OnConnect  []WSMessage `yaml:"onConnect,omitempty" json:"onConnect,omitempty"`
+	Replies    []WSReply   `yaml:"replies,omitempty" json:"replies,omitempty"`
+	CloseCode  int         `yaml:"closeCode,omitempty" json:"closeCode,omitempty"`
+	CloseAfter int         `yaml:"closeAfter,omitempty" json:"closeAfter,omitempty"`
+}
+
+// Payload Message bytes to send
+func (m WSMessage) Payload() ([]byte, error) {
+	if m.Type == WSBinary {
+		return base64.StdEncoding.DecodeString(m.Data)
+	}
+
+	return []byte(m.Data), nil
+}
+
+func (m WSMessage) validate() error {
+	switch m.Type {
+	case "", WSText, WSBinary:
+	default:
+		return fmt.Errorf("unknown websocket message type %q", m.Type)
+	}
+	if m.Delay < 0 {
+		return fmt.Errorf("websocket message delay must not be negative")
+	}
+	if _, err := m.Payload(); err != nil {
+		return fmt.Errorf("invalid binary websocket message: %s", err)
+	}
+
+	return nil
+}
+
+// Reply Find first reply for incoming message
+func (ws *WebSocketScript) Reply(message []byte) (*WSReply, bool) {
+	req := &RequestData{Body: message}
+
+outer:
+	for i, reply := range ws.Replies {
+		for _, m := range reply.Match {
+			if !m.Matches(req) {
+				continue outer
+			}
+		}
+		return &ws.Replies[i], true
+	}
+
+	return nil, false
+}
+
+// Validate Check WebSocket script
+func (ws *WebSocketScript) Validate() error {
+	if ws == nil {
+		return nil
+	}
+
+	for _, m := range ws.OnConnect {
+		if err := m.validate(); err != nil {
+			return err
+		}
+	}
+	for _, reply := range ws.Replies {
+		for _, m := range reply.Match {
+			if err := m.Validate(); err != nil {
+				return err
+			}
+		}
+		for _, m := range reply.Messages {
+			if err := m.validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ws.CloseCode != 0 && (ws.CloseCode < 1000 || ws.CloseCode > 4999 || ws.CloseCode == 1005 || ws.CloseCode == 1006) {
+		return fmt.Errorf("invalid websocket close code %d", ws.CloseCode)
+	}
+	if ws.CloseAfter < 0 {
+		return fmt.Errorf("websocket close delay must not be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package targets
+
+import (
+	"fmt"
+	"github.com/overdone/stubrouter/internal/latency"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChaosRule Flakiness added to proxied requests with given probability.
+// Rule applies to requests which target path starts with Path, to all requests if Path is empty
+type ChaosRule struct {
+	Probability float64          `yaml:"probability" json:"probability"`
+	Path        string           `yaml:"path,omitempty" json:"path,omitempty"`
+	Latency     *latency.Profile `yaml:"latency,omitempty" json:"latency,omitempty"`
+	Status      int              `yaml:"status,omitempty" json:"status,omitempty"`
+	Body        string           `yaml:"body,omitempty" json:"body,omitempty"`
+	Drop        bool             `yaml:"drop,omitempty" json:"drop,omitempty"`
+}
+
+// Validate Check rule params
+func (c ChaosRule) Validate() error {
+	if c.Probability < 0 || c.Probability > 1 {
+		return fmt.Errorf("chaos rule probability must be in [0, 1] range")
+	}
+	if c.Status != 0 && http.StatusText(c.Status) == "" {
+		return fmt.Errorf("invalid chaos rule status %d", c.Status)
+	}
+	if c.Latency == nil && c.Status == 0 && !c.Drop {
+		return fmt.Errorf("chaos rule has no effect")
+	}
+
+	return c.Latency.Validate()
+}
+
+// Applies Check rule applies to request target path
+func (c ChaosRule) Applies(path string) bool {
+	return c.Path == "" || strings.HasPrefix(path, c.Path)
+}
+
+// loadChaosRules Read target chaos rules from YAML file
+func loadChaosRules(filename string) ([]ChaosRule, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []ChaosRule
+	if err = yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid chaos rules file %s: %s", filename, err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,23 @@
+package targets
+
+import (
+	"fmt"
+	"github.com/overdone/stubrouter/internal/openapi"
+	"os"
+	"path/filepath"
+)
+
+// loadContract Read target OpenAPI contract from YAML or JSON file
+func loadContract(filename string) (*openapi.Document, error) {
+	data, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contract file %s: %s", filename, err)
+	}
+
+	return doc, nil
+}
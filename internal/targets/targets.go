@@ -0,0 +1,219 @@
+package targets
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/overdone/stubrouter/internal/config"
+	"github.com/overdone/stubrouter/internal/openapi"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Mode Target operating mode
+type Mode string
+
+const (
+	// ModeHybrid Respond with stub if found, proxy request otherwise
+	ModeHybrid Mode = "hybrid"
+	// ModeStub Respond with stubs only, unmatched requests get UnmatchedCode response
+	ModeStub Mode = "stub"
+	// ModePassthrough Proxy all requests, stubs are ignored
+	ModePassthrough Mode = "passthrough"
+	// ModeRecord Proxy all requests and save upstream responses as stubs
+	ModeRecord Mode = "record"
+)
+
+// Settings Target settings which can be changed at runtime
+type Settings struct {
+	Mode          Mode        `json:"mode"`
+	UnmatchedCode int         `json:"unmatchedCode"`
+	Chaos         []ChaosRule `json:"chaos"`
+	Bandwidth     int         `json:"bandwidth"`
+
+	// Contract OpenAPI document stubs and upstream responses are checked against, attached with contract API
+	Contract         *openapi.Document `json:"-"`
+	ValidateUpstream bool              `json:"validateUpstream"`
+}
+
+// Registry Runtime settings of configured targets by target path
+type Registry struct {
+	mu       sync.RWMutex
+	settings map[string]*Settings
+	hosts    map[string]string
+}
+
+// ParseMode Check mode name
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeHybrid, ModeStub, ModePassthrough, ModeRecord:
+		return m, nil
+	case "":
+		return ModeHybrid, nil
+	default:
+		return "", fmt.Errorf("unknown target mode %q", s)
+	}
+}
+
+// Validate Check settings values
+func (s *Settings) Validate() error {
+	if _, err := ParseMode(string(s.Mode)); err != nil {
+		return err
+	}
+	if http.StatusText(s.UnmatchedCode) == "" {
+		return fmt.Errorf("invalid unmatched response code %d", s.UnmatchedCode)
+	}
+	if s.Bandwidth < 0 {
+		return fmt.Errorf("bandwidth must not be negative")
+	}
+	for _, rule := range s.Chaos {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.ValidateUpstream && s.Contract == nil {
+		return fmt.Errorf("upstream validation requires OpenAPI contract")
+	}
+
+	return nil
+}
+
+// Merge Apply JSON with changed settings fields, fields missing in JSON are kept.
+// Passed lists replace current ones, decoder would merge them element by element otherwise
+func (s *Settings) Merge(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if _, ok := fields["chaos"]; ok {
+		s.Chaos = nil
+	}
+
+	return json.Unmarshal(data, s)
+}
+
+// NewRegistry Init settings for all configured targets
+func NewRegistry(cfg *config.StubRouterConfig) (*Registry, error) {
+	for path := range cfg.Modes {
+		if _, ok := cfg.Targets[path]; !ok {
+			return nil, fmt.Errorf("mode set for unknown target %s", path)
+		}
+	}
+	for path := range cfg.Chaos {
+		if _, ok := cfg.Targets[path]; !ok {
+			return nil, fmt.Errorf("chaos rules set for unknown target %s", path)
+		}
+	}
+	for path := range cfg.Bandwidth {
+		if _, ok := cfg.Targets[path]; !ok {
+			return nil, fmt.Errorf("bandwidth set for unknown target %s", path)
+		}
+	}
+	for path := range cfg.Contracts {
+		if _, ok := cfg.Targets[path]; !ok {
+			return nil, fmt.Errorf("OpenAPI contract set for unknown target %s", path)
+		}
+	}
+	validateUpstream := make(map[string]bool)
+	for _, path := range cfg.ValidateUpstream {
+		if _, ok := cfg.Targets[path]; !ok {
+			return nil, fmt.Errorf("upstream validation set for unknown target %s", path)
+		}
+		validateUpstream[path] = true
+	}
+
+	reg := &Registry{settings: make(map[string]*Settings), hosts: make(map[string]string)}
+	for path := range cfg.Targets {
+		mode, err := ParseMode(cfg.Modes[path])
+		if err != nil {
+			return nil, err
+		}
+
+		s := &Settings{Mode: mode, UnmatchedCode: cfg.UnmatchedCode, Bandwidth: cfg.Bandwidth[path]}
+		if filename, ok := cfg.Chaos[path]; ok {
+			if s.Chaos, err = loadChaosRules(filename); err != nil {
+				return nil, err
+			}
+		}
+
+		if filename, ok := cfg.Contracts[path]; ok {
+			if s.Contract, err = loadContract(filename); err != nil {
+				return nil, err
+			}
+		}
+		s.ValidateUpstream = validateUpstream[path]
+
+		if err = s.Validate(); err != nil {
+			return nil, err
+		}
+		reg.settings[path] = s
+		reg.hosts[path] = cfg.Targets[path]
+	}
+
+	return reg, nil
+}
+
+// Get Copy of target settings
+func (reg *Registry) Get(path string) (Settings, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	s, ok := reg.settings[path]
+	if !ok {
+		return Settings{}, false
+	}
+
+	return *s, true
+}
+
+// All Copy of all targets settings
+func (reg *Registry) All() map[string]Settings {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	all := make(map[string]Settings, len(reg.settings))
+	for path, s := range reg.settings {
+		all[path] = *s
+	}
+
+	return all
+}
+
+// ForHost Copies of settings of targets proxied to host, stubs of these targets are stored for host
+func (reg *Registry) ForHost(host *url.URL) map[string]Settings {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	found := make(map[string]Settings)
+	for path, target := range reg.hosts {
+		u, err := url.Parse(target)
+		if err == nil && u.Scheme == host.Scheme && u.Host == host.Host {
+			found[path] = *reg.settings[path]
+		}
+	}
+
+	return found
+}
+
+// Update Change target settings. Settings are changed only if update func succeeds and result is valid
+func (reg *Registry) Update(path string, update func(s *Settings) error) (Settings, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	s, ok := reg.settings[path]
+	if !ok {
+		return Settings{}, fmt.Errorf("target %s not found", path)
+	}
+
+	updated := *s
+	if err := update(&updated); err != nil {
+		return *s, err
+	}
+	if err := updated.Validate(); err != nil {
+		return *s, err
+	}
+	*s = updated
+
+	return updated, nil
+}
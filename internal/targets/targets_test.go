@@ -0,0 +1,105 @@
+package targets
+
+import (
+	"github.com/overdone/stubrouter/internal/config"
+	"net/http"
+	"testing"
+)
+
+func testConfig() *config.StubRouterConfig {
+	var cfg config.StubRouterConfig
+	cfg.Targets = map[string]string{"/app1": "http://127.0.0.1:9090", "/app2": "http://127.0.0.1:9091"}
+	cfg.UnmatchedCode = http.StatusNotFound
+	return &cfg
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name string
+		want Mode
+		ok   bool
+	}{
+		{"", ModeHybrid, true},
+		{"hybrid", ModeHybrid, true},
+		{"stub", ModeStub, true},
+		{"passthrough", ModePassthrough, true},
+		{"record", ModeRecord, true},
+		{"proxy", "", false},
+		{"STUB", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.name)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q, ok %t", tt.name, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	cfg := testConfig()
+	cfg.Modes = map[string]string{"/app1": "stub"}
+	reg, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("NewRegistry error: %s", err)
+	}
+
+	if s, _ := reg.Get("/app1"); s.Mode != ModeStub || s.UnmatchedCode != http.StatusNotFound {
+		t.Errorf("/app1 settings %+v, want stub mode", s)
+	}
+	if s, _ := reg.Get("/app2"); s.Mode != ModeHybrid {
+		t.Errorf("/app2 settings %+v, want hybrid mode by default", s)
+	}
+	if _, ok := reg.Get("/none"); ok {
+		t.Error("settings of unknown target found")
+	}
+
+	for _, modes := range []map[string]string{{"/none": "stub"}, {"/app1": "proxy"}} {
+		cfg = testConfig()
+		cfg.Modes = modes
+		if _, err = NewRegistry(cfg); err == nil {
+			t.Errorf("NewRegistry with modes %v succeeded", modes)
+		}
+	}
+}
+
+func TestRegistryUpdate(t *testing.T) {
+	reg, err := NewRegistry(testConfig())
+	if err != nil {
+		t.Fatalf("NewRegistry error: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		ok   bool
+		mode Mode
+		code int
+	}{
+		{"switch to stub", `{"mode": "stub", "unmatchedCode": 501}`, true, ModeStub, 501},
+		{"other fields kept", `{"bandwidth": 100}`, true, ModeStub, 501},
+		{"unknown mode", `{"mode": "proxy"}`, false, ModeStub, 501},
+		{"invalid code", `{"mode": "hybrid", "unmatchedCode": 1000}`, false, ModeStub, 501},
+		{"switch to passthrough", `{"mode": "passthrough"}`, true, ModePassthrough, 501},
+	}
+
+	for _, tt := range tests {
+		_, err = reg.Update("/app1", func(s *Settings) error {
+			return s.Merge([]byte(tt.data))
+		})
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Update error %v, want ok %t", tt.name, err, tt.ok)
+		}
+		// Failed update leaves settings unchanged
+		if s, _ := reg.Get("/app1"); s.Mode != tt.mode || s.UnmatchedCode != tt.code {
+			t.Errorf("%s: settings %+v, want mode %s and code %d", tt.name, s, tt.mode, tt.code)
+		}
+	}
+
+	if s, _ := reg.Get("/app2"); s.Mode != ModeHybrid {
+		t.Errorf("/app2 settings %+v changed", s)
+	}
+	if _, err = reg.Update("/none", func(s *Settings) error { return nil }); err == nil {
+		t.Error("Update of unknown target succeeded")
+	}
+}
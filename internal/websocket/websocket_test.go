@@ -0,0 +1,361 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient Client side of connection writing masked frames
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// newPipe Server connection and client connected with in-memory pipe
+func newPipe(t *testing.T) (*Conn, *testClient) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	conn := &Conn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+	return conn, &testClient{t: t, conn: client, r: bufio.NewReader(client)}
+}
+
+// encodeFrame Client frame, masked unless mask is nil
+func encodeFrame(fin bool, opcode byte, payload []byte, mask []byte) []byte {
+	var b bytes.Buffer
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	b.WriteByte(first)
+
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b.WriteByte(maskBit | byte(n))
+	case n <= 0xFFFF:
+		b.WriteByte(maskBit | 126)
+		binary.Write(&b, binary.BigEndian, uint16(n))
+	default:
+		b.WriteByte(maskBit | 127)
+		binary.Write(&b, binary.BigEndian, uint64(n))
+	}
+
+	if mask == nil {
+		b.Write(payload)
+		return b.Bytes()
+	}
+	b.Write(mask)
+	for i, c := range payload {
+		b.WriteByte(c ^ mask[i%4])
+	}
+
+	return b.Bytes()
+}
+
+// send Write frame asynchronously, pipe writes block until server reads them
+func (tc *testClient) send(data []byte) {
+	go func() {
+		_, _ = tc.conn.Write(data)
+	}()
+}
+
+// read Read unmasked server frame
+func (tc *testClient) read() frame {
+	tc.t.Helper()
+	_ = tc.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var head [2]byte
+	if _, err := io.ReadFull(tc.r, head[:]); err != nil {
+		tc.t.Fatalf("read frame: %s", err)
+	}
+	if head[1]&0x80 != 0 {
+		tc.t.Fatal("server frame is masked")
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, _ = io.ReadFull(tc.r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, _ = io.ReadFull(tc.r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(tc.r, payload); err != nil {
+		tc.t.Fatalf("read payload: %s", err)
+	}
+
+	return frame{fin: head[0]&0x80 != 0, opcode: head[0] & 0x0F, payload: payload}
+}
+
+// readClose Read frame expecting close with code
+func (tc *testClient) readClose(code int) {
+	tc.t.Helper()
+	f := tc.read()
+	if f.opcode != OpClose || len(f.payload) < 2 {
+		tc.t.Fatalf("got opcode %d payload %q, want close frame", f.opcode, f.payload)
+	}
+	if got := int(binary.BigEndian.Uint16(f.payload)); got != code {
+		tc.t.Errorf("close code = %d, want %d", got, code)
+	}
+}
+
+var testMask = []byte{0x12, 0x34, 0x56, 0x78}
+
+func TestAcceptKey(t *testing.T) {
+	// Sample from RFC 6455 section 1.3
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("AcceptKey = %q", got)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	tests := []struct {
+		connection string
+		upgrade    string
+		want       bool
+	}{
+		{"Upgrade", "websocket", true},
+		{"keep-alive, Upgrade", "WebSocket", true},
+		{"keep-alive", "websocket", false},
+		{"Upgrade", "h2c", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.connection != "" {
+			r.Header.Set("Connection", tt.connection)
+		}
+		if tt.upgrade != "" {
+			r.Header.Set("Upgrade", tt.upgrade)
+		}
+		if got := IsUpgrade(r); got != tt.want {
+			t.Errorf("IsUpgrade(Connection: %q, Upgrade: %q) = %v, want %v", tt.connection, tt.upgrade, got, tt.want)
+		}
+	}
+}
+
+func TestUpgradeRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+	}{
+		{"post", http.MethodPost, map[string]string{"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "a2V5"}},
+		{"not upgrade", http.MethodGet, map[string]string{"Connection": "keep-alive", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "a2V5"}},
+		{"wrong version", http.MethodGet, map[string]string{"Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "a2V5"}},
+		{"no key", http.MethodGet, map[string]string{"Sec-WebSocket-Version": "13"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			r.Header.Set("Connection", "Upgrade")
+			r.Header.Set("Upgrade", "websocket")
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			if _, err := Upgrade(httptest.NewRecorder(), r); err == nil {
+				t.Error("request upgraded")
+			}
+		})
+	}
+}
+
+func TestUpgradeHandshake(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.WriteMessage(op, msg)
+	}))
+	defer srv.Close()
+
+	netConn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	defer netConn.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if err = req.Write(netConn); err != nil {
+		t.Fatalf("write handshake: %s", err)
+	}
+
+	r := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatalf("read handshake: %s", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		t.Errorf("Upgrade = %q", resp.Header.Get("Upgrade"))
+	}
+
+	tc := &testClient{t: t, conn: netConn, r: r}
+	tc.send(encodeFrame(true, OpText, []byte("hello"), testMask))
+	f := tc.read()
+	if f.opcode != OpText || string(f.payload) != "hello" {
+		t.Errorf("echo = %d %q, want text hello", f.opcode, f.payload)
+	}
+}
+
+func TestReadMessageLengths(t *testing.T) {
+	for _, n := range []int{0, 1, 125, 126, 200, 0xFFFF, 0x10000, 70000} {
+		conn, tc := newPipe(t)
+		payload := bytes.Repeat([]byte("abcdefg"), n/7+1)[:n]
+
+		tc.send(encodeFrame(true, OpBinary, payload, testMask))
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("length %d: %s", n, err)
+		}
+		if op != OpBinary || !bytes.Equal(msg, payload) {
+			t.Errorf("length %d: got opcode %d and %d bytes", n, op, len(msg))
+		}
+	}
+}
+
+func TestWriteMessageLengths(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		conn, tc := newPipe(t)
+		payload := bytes.Repeat([]byte{'x'}, n)
+
+		errc := make(chan error, 1)
+		go func() { errc <- conn.WriteMessage(OpText, payload) }()
+		f := tc.read()
+		if err := <-errc; err != nil {
+			t.Fatalf("length %d: %s", n, err)
+		}
+		if !f.fin || f.opcode != OpText || !bytes.Equal(f.payload, payload) {
+			t.Errorf("length %d: got fin %v opcode %d and %d bytes", n, f.fin, f.opcode, len(f.payload))
+		}
+	}
+}
+
+func TestReadFragmented(t *testing.T) {
+	conn, tc := newPipe(t)
+
+	var data []byte
+	data = append(data, encodeFrame(false, OpText, []byte("hel"), testMask)...)
+	data = append(data, encodeFrame(true, OpPing, []byte("p"), testMask)...)
+	data = append(data, encodeFrame(false, OpContinuation, []byte("lo "), testMask)...)
+	data = append(data, encodeFrame(true, OpContinuation, []byte("world"), testMask)...)
+	tc.send(data)
+
+	pong := make(chan frame, 1)
+	go func() { pong <- tc.read() }()
+
+	op, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op != OpText || string(msg) != "hello world" {
+		t.Errorf("got %d %q, want text %q", op, msg, "hello world")
+	}
+
+	f := <-pong
+	if f.opcode != OpPong || string(f.payload) != "p" {
+		t.Errorf("ping answered with %d %q, want pong", f.opcode, f.payload)
+	}
+}
+
+func TestReadClose(t *testing.T) {
+	conn, tc := newPipe(t)
+
+	payload := []byte{0x03, 0xE9} // 1001 going away
+	tc.send(encodeFrame(true, OpClose, payload, testMask))
+
+	done := make(chan struct{})
+	go func() {
+		tc.readClose(1001)
+		close(done)
+	}()
+
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Errorf("ReadMessage error = %v, want ErrClosed", err)
+	}
+	<-done
+
+	if err := conn.WriteMessage(OpText, []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("write after close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestReadProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		code int
+	}{
+		{"unexpected continuation", encodeFrame(true, OpContinuation, []byte("x"), testMask), CloseProtocolError},
+		{"unexpected data frame", append(encodeFrame(false, OpText, []byte("a"), testMask), encodeFrame(true, OpText, []byte("b"), testMask)...), CloseProtocolError},
+		{"unknown opcode", encodeFrame(true, 0x3, []byte("x"), testMask), CloseProtocolError},
+		{"unmasked frame", encodeFrame(true, OpText, []byte("x"), nil), CloseProtocolError},
+		{"too big", []byte{0x82, 0xFF, 0, 0, 0, 0, 0x10, 0, 0, 0}, CloseTooBig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, tc := newPipe(t)
+			tc.send(tt.data)
+
+			done := make(chan struct{})
+			go func() {
+				tc.readClose(tt.code)
+				close(done)
+			}()
+
+			if _, _, err := conn.ReadMessage(); err == nil {
+				t.Error("message accepted")
+			}
+			<-done
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package xpath
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Supported syntax subset:
+//   /a/b       child elements, * matches any element
+//   //b        descendant elements
+//   [n]        position among same parent elements selected by step, starting from 1
+//   [@x]       element has attribute, [@x='v'] attribute equals value
+//   [c='v']    child element text equals value, [text()='v'] own text equals value
+//   /@x        attribute value as last step
+//   /text()    element text as last step
+// Namespaces are ignored, elements and attributes are matched by local name
+
+// Node XML element
+type Node struct {
+	Name     string
+	Attrs    map[string]string
+	Children []*Node
+	Text     string
+}
+
+type predicate struct {
+	index int
+	attr  string
+	child string
+	value *string
+}
+
+type step struct {
+	descendant bool
+	name       string
+	attr       string
+	text       bool
+	predicates []predicate
+}
+
+// Parse Read XML document into element tree under virtual root node
+func Parse(data []byte) (*Node, error) {
+	root := &Node{}
+	stack := []*Node{root}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &Node{Name: t.Name.Local, Attrs: make(map[string]string)}
+			for _, a := range t.Attr {
+				n.Attrs[a.Name.Local] = a.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top := stack[len(stack)-1]
+			top.Text += string(t)
+		}
+	}
+
+	if len(root.Children) == 0 {
+		return nil, fmt.Errorf("xml document is empty")
+	}
+
+	return root, nil
+}
+
+// Eval Select string values from XML document: element text or attribute values
+func Eval(expr string, data []byte) ([]string, error) {
+	steps, err := compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*Node{root}
+	for i, s := range steps {
+		if s.attr != "" || s.text {
+			if i != len(steps)-1 {
+				return nil, fmt.Errorf("xpath %q: attribute or text() must be the last step", expr)
+			}
+			return s.values(nodes), nil
+		}
+
+		var next []*Node
+		for _, n := range nodes {
+			next = append(next, s.apply(n)...)
+		}
+		nodes = next
+	}
+
+	values := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		values = append(values, strings.TrimSpace(n.Text))
+	}
+
+	return values, nil
+}
+
+// Validate Check expression syntax
+func Validate(expr string) error {
+	_, err := compile(expr)
+	return err
+}
+
+func compile(expr string) ([]step, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("xpath %q must be absolute", expr)
+	}
+
+	var steps []step
+	rest := expr
+	for rest != "" {
+		var s step
+		if strings.HasPrefix(rest, "//") {
+			s.descendant = true
+			rest = rest[2:]
+		} else if strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+		} else {
+			return nil, fmt.Errorf("xpath %q: unexpected %q", expr, rest)
+		}
+
+		end := stepEnd(rest)
+		raw := rest[:end]
+		rest = rest[end:]
+
+		name := raw
+		if i := strings.Index(raw, "["); i >= 0 {
+			name = raw[:i]
+			preds, err := parsePredicates(raw[i:])
+			if err != nil {
+				return nil, fmt.Errorf("xpath %q: %s", expr, err)
+			}
+			s.predicates = preds
+		}
+
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("xpath %q: empty step", expr)
+		case name == "text()":
+			s.text = true
+		case strings.HasPrefix(name, "@"):
+			s.attr = name[1:]
+		default:
+			s.name = name
+		}
+
+		steps = append(steps, s)
+	}
+
+	return steps, nil
+}
+
+// stepEnd Position of next step separator outside of brackets and quotes
+func stepEnd(s string) int {
+	return scan(s, func(c byte, depth int) bool { return c == '/' && depth == 0 })
+}
+
+// predicateEnd Position of bracket closing predicate started at s beginning, -1 if it is not closed
+func predicateEnd(s string) int {
+	end := scan(s, func(c byte, depth int) bool { return c == ']' && depth == 0 })
+	if end == len(s) {
+		return -1
+	}
+
+	return end
+}
+
+// scan Position of first char outside of quotes satisfying stop with bracket depth before the char,
+// string length if there is no such char
+func scan(s string, stop func(c byte, depth int) bool) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if stop(c, depth) {
+				return i
+			}
+		case stop(c, depth):
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+func parsePredicates(s string) ([]predicate, error) {
+	var preds []predicate
+	for s != "" {
+		if !strings.HasPrefix(s, "[") {
+			return nil, fmt.Errorf("unexpected %q", s)
+		}
+		end := predicateEnd(s)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed [")
+		}
+
+		body := strings.TrimSpace(s[1:end])
+		s = s[end+1:]
+
+		if idx, err := strconv.Atoi(body); err == nil {
+			preds = append(preds, predicate{index: idx})
+			continue
+		}
+
+		var p predicate
+		left := body
+		if i := strings.Index(body, "="); i > 0 {
+			left = strings.TrimSpace(body[:i])
+			v := strings.TrimSpace(body[i+1:])
+			if len(v) < 2 || (v[0] != '\'' && v[0] != '"') || v[0] != v[len(v)-1] {
+				return nil, fmt.Errorf("invalid predicate value %s", v)
+			}
+			v = v[1 : len(v)-1]
+			p.value = &v
+		}
+
+		if strings.HasPrefix(left, "@") {
+			p.attr = left[1:]
+		} else {
+			p.child = left
+		}
+		preds = append(preds, p)
+	}
+
+	return preds, nil
+}
+
+// apply Elements selected by step from node. Predicates filter children of every parent separately,
+// so //b[1] selects first b of each parent
+func (s step) apply(n *Node) []*Node {
+	if !s.descendant {
+		return s.children(n)
+	}
+
+	var found []*Node
+	var walk func(*Node)
+	walk = func(p *Node) {
+		found = append(found, s.children(p)...)
+		for _, c := range p.Children {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return found
+}
+
+// children Node children matching step name and predicates
+func (s step) children(n *Node) []*Node {
+	var found []*Node
+	for _, c := range n.Children {
+		if s.name == "*" || c.Name == s.name {
+			found = append(found, c)
+		}
+	}
+
+	for _, p := range s.predicates {
+		found = p.filter(found)
+	}
+
+	return found
+}
+
+func (s step) values(nodes []*Node) []string {
+	var values []string
+	for _, n := range nodes {
+		targets := []*Node{n}
+		if s.descendant {
+			targets = nil
+			var walk func(*Node)
+			walk = func(p *Node) {
+				targets = append(targets, p)
+				for _, c := range p.Children {
+					walk(c)
+				}
+			}
+			walk(n)
+		}
+
+		for _, t := range targets {
+			if s.text {
+				values = append(values, strings.TrimSpace(t.Text))
+			} else if v, ok := t.Attrs[s.attr]; ok {
+				values = append(values, v)
+			}
+		}
+	}
+
+	return values
+}
+
+func (p predicate) filter(nodes []*Node) []*Node {
+	if p.index > 0 {
+		if p.index <= len(nodes) {
+			return []*Node{nodes[p.index-1]}
+		}
+		return nil
+	}
+
+	var found []*Node
+	for _, n := range nodes {
+		if p.matches(n) {
+			found = append(found, n)
+		}
+	}
+
+	return found
+}
+
+func (p predicate) matches(n *Node) bool {
+	switch {
+	case p.attr != "":
+		v, ok := n.Attrs[p.attr]
+		return ok && (p.value == nil || v == *p.value)
+	case p.child == "text()":
+		return p.value == nil || strings.TrimSpace(n.Text) == *p.value
+	default:
+		for _, c := range n.Children {
+			if c.Name == p.child && (p.value == nil || strings.TrimSpace(c.Text) == *p.value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
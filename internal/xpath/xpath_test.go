@@ -0,0 +1,106 @@
+package xpath
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDoc = `<?xml version="1.0"?>
+<order id="42" xmlns:x="urn:x">
+  <customer type="vip">Ann</customer>
+  <items>
+    <item sku="A1" qty="2">Apple</item>
+    <item sku="B2">Banana</item>
+  </items>
+  <items>
+    <item sku="C3">Cherry</item>
+  </items>
+  <note x:lang="en">a]b</note>
+</order>`
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{"root element text", "/order/customer", []string{"Ann"}},
+		{"attribute", "/order/@id", []string{"42"}},
+		{"wildcard", "/order/*/@type", []string{"vip"}},
+		{"descendant", "//item/@sku", []string{"A1", "B2", "C3"}},
+		{"text step", "/order/customer/text()", []string{"Ann"}},
+		{"position", "/order/items/item[2]", []string{"Banana"}},
+		{"position out of range", "/order/items/item[5]", []string{}},
+		{"descendant position per parent", "//item[1]/@sku", []string{"A1", "C3"}},
+		{"attribute exists", "//item[@qty]", []string{"Apple"}},
+		{"attribute equals", "//item[@sku='B2']", []string{"Banana"}},
+		{"attribute double quotes", `//item[@sku="C3"]`, []string{"Cherry"}},
+		{"child text equals", "/order/items[item='Cherry']/item/@sku", []string{"C3"}},
+		{"own text equals", "//item[text()='Apple']/@sku", []string{"A1"}},
+		{"several predicates", "//item[@sku][2]", []string{"Banana"}},
+		{"namespaced attribute by local name", "/order/note/@lang", []string{"en"}},
+		{"bracket in quoted value", "/order/note[text()='a]b']/@lang", []string{"en"}},
+		{"no match", "/order/missing", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Eval(tt.expr, []byte(testDoc))
+			if err != nil {
+				t.Fatalf("Eval(%q) error: %s", tt.expr, err)
+			}
+			if got == nil {
+				got = []string{}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalQuotedPredicate(t *testing.T) {
+	got, err := Eval("/a/b[@x='q]r']", []byte(`<a><b x="q]r">1</b><b x="q">2</b></a>`))
+	if err != nil {
+		t.Fatalf("Eval error: %s", err)
+	}
+	if !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("Eval = %q, want [1]", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"a/b", "must be absolute"},
+		{"/a//", "empty step"},
+		{"/a/b[@x='v'", "unclosed ["},
+		{"/a/b[@x=v]", "invalid predicate value"},
+		{"/a/b[@x='v]", "unclosed ["},
+		{"/a/b[1]x", "unexpected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := Validate(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Validate(%q) = %v, want error containing %q", tt.expr, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	if _, err := Eval("/a/@x/b", []byte("<a x='1'><b/></a>")); err == nil {
+		t.Error("attribute step in the middle accepted")
+	}
+	if _, err := Eval("/a", []byte("")); err == nil {
+		t.Error("empty document accepted")
+	}
+	if _, err := Eval("/a", []byte("<a><b></a>")); err == nil {
+		t.Error("malformed document accepted")
+	}
+}
@@ -0,0 +1,130 @@
+// MAX_EXCHANGES Number of exchanges kept on page, older ones are removed
+const MAX_EXCHANGES = 200;
+
+let eventSource = null;
+let paused = false;
+
+
+function escapeHtml(value) {
+    return String(value)
+        .replaceAll('&', '&amp;')
+        .replaceAll('"', '&quot;')
+        .replaceAll('<', '&lt;')
+        .replaceAll('>', '&gt;');
+}
+
+
+function formatHeaders(headers) {
+    return Object.entries(headers || {})
+        .flatMap(([k, values]) => values.map((v) => `${k}: ${v}`))
+        .join('\n');
+}
+
+
+function formatBody(body, encoding, truncated) {
+    if (!body) return '';
+    let text = encoding === 'base64' ? `[base64] ${body}` : body;
+    if (truncated) text += '\n[truncated]';
+    return text;
+}
+
+
+function formatQuery(query) {
+    const q = new URLSearchParams(
+        Object.entries(query || {}).flatMap(([k, values]) => values.map((v) => [k, v])),
+    ).toString();
+    return q ? `?${q}` : '';
+}
+
+
+function createExchangeElement(e) {
+    const source = e.stubbed
+        ? `<span class="stubbed">stub ${escapeHtml(e.stub)}</span>`
+        : (e.unmatched ? 'proxied, no stub matched' : 'proxied');
+    const statusClass = e.status >= 400 ? 'status-error' : '';
+    return `
+        <li class="exchange">
+            <details>
+                <summary>
+                    ${escapeHtml(new Date(e.time).toLocaleTimeString())}
+                    <span class="${statusClass}">${e.status}</span>
+                    ${escapeHtml(e.method)} ${escapeHtml(e.target + e.path + formatQuery(e.query))}
+                    ${e.durationMs} ms, ${source}
+                </summary>
+                <div class="exchange-body">
+                    <div>
+                        <b>Request</b>
+                        <pre>${escapeHtml(formatHeaders(e.headers))}</pre>
+                        <pre>${escapeHtml(formatBody(e.body, e.bodyEncoding, e.bodyTruncated))}</pre>
+                    </div>
+                    <div>
+                        <b>Response</b>
+                        <pre>${escapeHtml(formatHeaders(e.response.headers))}</pre>
+                        <pre>${escapeHtml(formatBody(e.response.body, e.response.bodyEncoding, e.response.bodyTruncated))}</pre>
+                    </div>
+                </div>
+            </details>
+        </li>`;
+}
+
+
+function setStatus(text) {
+    document.querySelector('.inspector-status').innerText = text;
+}
+
+
+function onExchange(event) {
+    if (paused) return;
+
+    const list = document.querySelector('.list.exchanges');
+    list.insertAdjacentHTML('afterbegin', createExchangeElement(JSON.parse(event.data)));
+    while (list.children.length > MAX_EXCHANGES) {
+        list.lastElementChild.remove();
+    }
+}
+
+
+function connect(filter) {
+    if (eventSource) eventSource.close();
+
+    const params = new URLSearchParams(Object.entries(filter).filter(([, v]) => v));
+    eventSource = new EventSource(`/journalapi/live?${params}`);
+    eventSource.addEventListener('exchange', onExchange);
+    eventSource.onopen = () => setStatus('Connected');
+    eventSource.onerror = () => setStatus('Reconnecting...');
+}
+
+
+function onFilterSubmit(event) {
+    event.preventDefault();
+    const filter = Object.fromEntries(new FormData(event.target));
+    const url = new URL(window.location);
+    url.search = new URLSearchParams(Object.entries(filter).filter(([, v]) => v)).toString();
+    window.history.replaceState(null, '', url);
+    connect(filter);
+}
+
+
+function onPauseClick(el) {
+    paused = !paused;
+    el.value = paused ? 'Resume' : 'Pause';
+}
+
+
+function onClearClick() {
+    document.querySelector('.list.exchanges').innerHTML = '';
+}
+
+
+function onOpenInspectorPage() {
+    const params = new URLSearchParams(window.location.search);
+    const filter = {};
+    ['target', 'method', 'path'].forEach((name) => {
+        filter[name] = params.get(name) || '';
+        document.querySelector(`.inspector-filter [name="${name}"]`).value = filter[name];
+    });
+    connect(filter);
+}
+
+
+onOpenInspectorPage();
@@ -1,9 +1,63 @@
-function createStubFormElement(target, formData, isNew= false) {
+const STUB_METHODS = ['ANY', 'GET', 'HEAD', 'POST', 'PUT', 'PATCH', 'DELETE', 'OPTIONS'];
+const STUB_FORM_FIELDS = ['code', 'data', 'headers', 'timeout'];
+
+
+function escapeHtml(value) {
+    return String(value)
+        .replaceAll('&', '&amp;')
+        .replaceAll('"', '&quot;')
+        .replaceAll('<', '&lt;')
+        .replaceAll('>', '&gt;');
+}
+
+
+function parseStubKey(key) {
+    let name = '';
+    const nameIdx = key.lastIndexOf('#');
+    if (nameIdx >= 0) {
+        name = key.slice(nameIdx + 1);
+        key = key.slice(0, nameIdx);
+    }
+
+    const [method, ...path] = key.split(' ');
+    if (path.length && STUB_METHODS.includes(method)) {
+        return { method, path: path.join(' '), name };
+    }
+    return { method: 'ANY', path: key, name };
+}
+
+
+function getStubOptions(formData) {
+    const options = Object.fromEntries(
+        Object.entries(formData).filter(([k]) => ![...STUB_FORM_FIELDS, 'method', 'path', 'name'].includes(k)),
+    );
+    return Object.keys(options).length ? JSON.stringify(options, null, 2) : '';
+}
+
+
+function createMethodOptions(selected) {
+    return STUB_METHODS
+        .map((m) => `<option value="${m}" ${m === selected ? 'selected' : ''}>${m}</option>`)
+        .join('');
+}
+
+
+function createHitsElement(hits) {
+    if (!hits) return '<span class="stub-hits never-hit">never hit</span>';
+    const lastHit = new Date(hits.lastHit).toLocaleString();
+    return `<span class="stub-hits" title="Last hit ${escapeHtml(lastHit)}">${hits.count} hits</span>`;
+}
+
+
+function createStubFormElement(target, formData, isNew= false, hits = null) {
     return `
         <li class="stub">
             <form isnew=${isNew}>
                 <div class="stub-head">
-                    <input name="path" type="text" class="stub-name" ${isNew ? '' : 'readonly'} value="${formData.path || ''}" />
+                    <select name="method" class="stub-method" ${isNew ? '' : 'disabled'}>${createMethodOptions(formData.method || 'ANY')}</select>
+                    <input name="path" type="text" class="stub-name" ${isNew ? '' : 'readonly'} value="${escapeHtml(formData.path || '')}" />
+                    <input name="name" type="text" class="stub-variant" placeholder="Variant" ${isNew ? '' : 'readonly'} value="${escapeHtml(formData.name || '')}" />
+                    ${isNew ? '' : createHitsElement(hits)}
                     <div class="head-controls">
                         <input type="button" value="Save" class="button" onClick="onSaveStubClick(this, '${target}')" tabindex="0" />
                         <input type="button" value="Remove" class="button remove-button" onClick="onRemoveStubClick(this, '${target}')" tabindex="0" />
@@ -13,27 +67,54 @@ function createStubFormElement(target, formData, isNew= false) {
                 <textarea name="headers" rows="2" placeholder="Headers">${JSON.stringify(formData.headers) || '{}'}</textarea>
                 <textarea name="data" rows="5" placeholder="Data">${formData.data || ''}</textarea>
                 <input name="timeout" type="number" class="number" placeholder="Timeout, ms" value="${formData.timeout || ''}" />
+                <textarea name="options" rows="3" placeholder="Options: match conditions etc. in JSON">${escapeHtml(getStubOptions(formData))}</textarea>
             </form>
         </li>`;
 }
 
 
+function getStubFormData(stubForm) {
+    const formData = Object.fromEntries(new FormData(stubForm));
+    // Disabled select is not included to form data
+    formData.method = stubForm.querySelector('[name="method"]').value;
+    return formData;
+}
+
+
+function getStubData(formData) {
+    return {
+        ...JSON.parse(formData.options || '{}'),
+        code: Number(formData.code),
+        data: formData.data,
+        headers: JSON.parse(formData.headers || '{}'),
+        timeout: Number(formData.timeout) || 0,
+    };
+}
+
+
+function getStubParams(target, formData) {
+    return new URLSearchParams({ target, method: formData.method, path: formData.path, name: formData.name });
+}
+
+
 async function onSaveStubClick(el, target) {
     const stubForm = el.closest('form');
-    const formData = Object.fromEntries(new FormData(stubForm));
+    const formData = getStubFormData(stubForm);
 
     try {
         const resp = await fetch(
-        `/stubapi/?${new URLSearchParams({ target, path: formData.path })}`,
+        `/stubapi/?${getStubParams(target, formData)}`,
         {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify(formData),
+                body: JSON.stringify(getStubData(formData)),
             },
         );
         if (resp.status === 200) {
             stubForm.setAttribute('isnew', "false");
             stubForm.querySelector('[name="path"]').setAttribute('readonly', '');
+            stubForm.querySelector('[name="name"]').setAttribute('readonly', '');
+            stubForm.querySelector('[name="method"]').setAttribute('disabled', '');
         }
     } catch (e) {
         console.log(e);
@@ -43,12 +124,12 @@ async function onSaveStubClick(el, target) {
 
 async function onRemoveStubClick(el, target) {
     const stubForm = el.closest('form');
-    const formData = Object.fromEntries(new FormData(stubForm));
+    const formData = getStubFormData(stubForm);
 
     try {
         if (stubForm.getAttribute('isnew') === 'false') {
             const resp = await fetch(
-            `/stubapi/?${new URLSearchParams({ target, path: formData.path })}`,
+            `/stubapi/?${getStubParams(target, formData)}`,
             { method: 'DELETE' },
             );
             if (resp.status === 200) {
@@ -71,6 +152,64 @@ function onAddStubClick() {
 }
 
 
+function createNearMissElement(nearMiss) {
+    const mismatches = nearMiss.mismatches
+        .map((m) => `<li class="mismatch">${escapeHtml(m)}</li>`)
+        .join('');
+    return `
+        <li class="near-miss">
+            <span>${escapeHtml(nearMiss.stub)}</span>
+            <ul>${mismatches}</ul>
+        </li>`;
+}
+
+
+function createUnmatchedElement(entry) {
+    const query = new URLSearchParams(
+        Object.entries(entry.query || {}).flatMap(([k, values]) => values.map((v) => [k, v])),
+    ).toString();
+    const nearMisses = (entry.nearMisses || []).map(createNearMissElement).join('')
+        || '<li class="near-miss">No stubs for target</li>';
+    return `
+        <li class="request">
+            <div class="request-head">
+                <span>${escapeHtml(new Date(entry.time).toLocaleTimeString())} ${escapeHtml(entry.method)} ${escapeHtml(entry.path)}${query ? '?' + escapeHtml(query) : ''} &rarr; ${entry.status}</span>
+                <input type="button" value="Create stub" class="button" data-method="${escapeHtml(entry.method)}" data-path="${escapeHtml(entry.path)}" onClick="onCreateStubClick(this)" />
+            </div>
+            <ul>${nearMisses}</ul>
+        </li>`;
+}
+
+
+function onCreateStubClick(el) {
+    const { method, path } = el.dataset;
+    const params = new URLSearchParams(window.location.search);
+    const target = params.get('target');
+    const stubList = document.querySelector('.list.stubs');
+    stubList.insertAdjacentHTML('beforeend', createStubFormElement(target, { method, path }, true));
+}
+
+
+async function loadUnmatchedRequests() {
+    const params = new URLSearchParams(window.location.search);
+    const target = params.get('target');
+    if (!target) return;
+
+    try {
+        const resp = await fetch(`/stubapi/unmatched?${new URLSearchParams({ target, limit: 20 })}`);
+        const entries = await resp.json();
+        const requestList = document.querySelector('.list.unmatched');
+
+        if (!requestList) return;
+
+        // Latest requests first
+        requestList.innerHTML = entries.reverse().map(createUnmatchedElement).join('');
+    } catch (e) {
+        console.log(e);
+    }
+}
+
+
 async function onOpenStubsPage() {
     const params = new URLSearchParams(window.location.search);
     const target = params.get('target');
@@ -82,13 +221,18 @@ async function onOpenStubsPage() {
     try {
         const resp = await fetch(`/stubapi/?${new URLSearchParams({ target })}`);
         const data = await resp.json();
+        const coverageResp = await fetch(`/stubapi/coverage?${new URLSearchParams({ target })}`);
+        const coverage = await coverageResp.json();
         const stubList = document.querySelector('.list.stubs');
 
         if (!stubList) return;
 
+        document.querySelector('.coverage').innerText =
+            `${coverage.hit} of ${coverage.stubs} stubs hit since start, ${coverage.neverHit.length} never hit`;
+
         Object.entries(data).forEach((s) => {
-            const formData = { path: s[0], ...s[1] };
-            stubList.insertAdjacentHTML('beforeend', createStubFormElement(target, formData));
+            const formData = { ...parseStubKey(s[0]), ...s[1] };
+            stubList.insertAdjacentHTML('beforeend', createStubFormElement(target, formData, false, coverage.hits[s[0]]));
         })
     } catch (e) {
         console.log(e);
@@ -97,6 +241,7 @@ async function onOpenStubsPage() {
 
 
 onOpenStubsPage().then().catch();
+loadUnmatchedRequests().then().catch();
 
 
 